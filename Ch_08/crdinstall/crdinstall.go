@@ -0,0 +1,329 @@
+// Package crdinstall installs and upgrades CustomResourceDefinitions, and
+// waits for the API server to serve them.
+package crdinstall
+
+import (
+	"bytes"
+	"context"
+	"embed"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"path"
+	"slices"
+	"strings"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/util/wait"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// Manifests holds the CRDs of the book, generated from their Go types. They
+// are copies of the CRDs generated in chapter 9, refreshed by go generate or
+// by make crd in Ch_09/github.com/myid/myresource-crd.
+//
+//go:generate cp ../../Ch_09/github.com/myid/myresource-crd/manifests/myresources.mygroup.example.com.yaml manifests/
+//go:embed manifests/*.yaml
+var Manifests embed.FS
+
+// _defaultTimeout bounds the wait for a CRD to be served, when
+// Options.Timeout is not set.
+const _defaultTimeout = time.Minute
+
+// _pollInterval is the interval between two checks of the conditions of a CRD.
+var _pollInterval = time.Second
+
+// Options tunes Install.
+type Options struct {
+	// AllowStoredVersionRemoval, when true, lets an upgrade remove versions
+	// that are still in the storedVersions of the CRD. The objects stored in
+	// these versions must have been migrated first, they are removed from
+	// storedVersions once a dry run of the update succeeded, and restored when
+	// the update fails.
+	AllowStoredVersionRemoval bool
+	// Timeout bounds the wait for each CRD to be served. It defaults to one
+	// minute.
+	Timeout time.Duration
+	// FieldManager is the manager of the fields written.
+	FieldManager string
+}
+
+// StoredVersionsError is returned when an upgrade removes versions in which
+// objects may still be stored.
+type StoredVersionsError struct {
+	// Name is the name of the CRD.
+	Name string
+	// Versions are the removed versions still in storedVersions.
+	Versions []string
+}
+
+func (e *StoredVersionsError) Error() string {
+	return fmt.Sprintf("CRD %s: versions %s are still in status.storedVersions, "+
+		"migrate their objects to the storage version before removing them",
+		e.Name, strings.Join(e.Versions, ", "))
+}
+
+// Load reads the CRDs of the YAML files of fsys matching pattern. A file can
+// hold several CRDs, as YAML documents.
+func Load(fsys fs.FS, pattern string) ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	files, err := fs.Glob(fsys, pattern)
+	if err != nil {
+		return nil, err
+	}
+	var crds []*apiextensionsv1.CustomResourceDefinition
+	for _, file := range files {
+		data, err := fs.ReadFile(fsys, file)
+		if err != nil {
+			return nil, err
+		}
+		decoder := utilyaml.NewYAMLOrJSONDecoder(bytes.NewReader(data), 4096)
+		for {
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			err := decoder.Decode(crd)
+			if errors.Is(err, io.EOF) {
+				break
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%s: %w", file, err)
+			}
+			if crd.Kind == "" && crd.Name == "" {
+				continue // empty document
+			}
+			if gvk := crd.GroupVersionKind(); gvk != apiextensionsv1.SchemeGroupVersion.WithKind("CustomResourceDefinition") {
+				return nil, fmt.Errorf("%s: %s is not a CustomResourceDefinition", file, gvk)
+			}
+			crds = append(crds, crd)
+		}
+	}
+	return crds, nil
+}
+
+// Embedded returns the CRDs of Manifests.
+func Embedded() ([]*apiextensionsv1.CustomResourceDefinition, error) {
+	return Load(Manifests, path.Join("manifests", "*.yaml"))
+}
+
+// Install creates the CRDs crds, or updates them when they exist, and waits
+// for each of them to be served: with its names accepted, established and its
+// served versions in discovery.
+func Install(ctx context.Context, client clientset.Interface, crds []*apiextensionsv1.CustomResourceDefinition, opts Options) error {
+	for _, crd := range crds {
+		if err := install(ctx, client, crd, opts); err != nil {
+			return err
+		}
+		if err := waitServed(ctx, client, crd, opts); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func install(ctx context.Context, client clientset.Interface, crd *apiextensionsv1.CustomResourceDefinition, opts Options) error {
+	crds := client.ApiextensionsV1().CustomResourceDefinitions()
+	live, err := crds.Get(ctx, crd.Name, metav1.GetOptions{})
+	if apierrors.IsNotFound(err) {
+		_, err = crds.Create(ctx, crd.DeepCopy(), metav1.CreateOptions{FieldManager: opts.FieldManager})
+		return err
+	}
+	if err != nil {
+		return err
+	}
+
+	updated := crd.DeepCopy()
+	updated.ResourceVersion = live.ResourceVersion
+	preserveInjected(live, updated)
+	removed := removedStoredVersions(live, crd)
+	if len(removed) == 0 {
+		_, err = crds.Update(ctx, updated, metav1.UpdateOptions{FieldManager: opts.FieldManager})
+		return err
+	}
+	if !opts.AllowStoredVersionRemoval {
+		return &StoredVersionsError{Name: crd.Name, Versions: removed}
+	}
+
+	// the API server refuses versions in storedVersions to be removed from
+	// the spec, so they are removed from storedVersions first. The update is
+	// validated beforehand by a dry run keeping them as unserved versions, and
+	// storedVersions are restored when it fails anyway.
+	if err := dryRunUpdate(ctx, client, live, updated, removed, opts); err != nil {
+		return err
+	}
+	stored := live.Status.StoredVersions
+	live.Status.StoredVersions = slices.DeleteFunc(slices.Clone(stored), func(version string) bool {
+		return slices.Contains(removed, version)
+	})
+	live, err = crds.UpdateStatus(ctx, live, metav1.UpdateOptions{FieldManager: opts.FieldManager})
+	if err != nil {
+		return err
+	}
+	updated.ResourceVersion = live.ResourceVersion
+	if _, err := crds.Update(ctx, updated, metav1.UpdateOptions{FieldManager: opts.FieldManager}); err != nil {
+		live.Status.StoredVersions = stored
+		if _, restoreErr := crds.UpdateStatus(ctx, live, metav1.UpdateOptions{FieldManager: opts.FieldManager}); restoreErr != nil {
+			return fmt.Errorf("CRD %s: versions %s removed from status.storedVersions but not from the spec, "+
+				"restore them: %w", crd.Name, strings.Join(removed, ", "), errors.Join(err, restoreErr))
+		}
+		return err
+	}
+	return nil
+}
+
+// dryRunUpdate validates the update of live to updated, the removed versions
+// being kept in the spec as unserved versions.
+func dryRunUpdate(ctx context.Context, client clientset.Interface, live, updated *apiextensionsv1.CustomResourceDefinition, removed []string, opts Options) error {
+	check := updated.DeepCopy()
+	for _, version := range live.Spec.Versions {
+		if slices.Contains(removed, version.Name) {
+			version.Served, version.Storage = false, false
+			check.Spec.Versions = append(check.Spec.Versions, version)
+		}
+	}
+	_, err := client.ApiextensionsV1().CustomResourceDefinitions().Update(ctx, check,
+		metav1.UpdateOptions{FieldManager: opts.FieldManager, DryRun: []string{metav1.DryRunAll}})
+	return err
+}
+
+// preserveInjected carries over to updated what is set on live out of band:
+// the CA bundle of the conversion webhook, injected by cert-manager or a
+// certificate rotator, and the labels and annotations missing from updated.
+// The update would drop them otherwise, and conversions would fail until the
+// CA bundle is injected again.
+func preserveInjected(live, updated *apiextensionsv1.CustomResourceDefinition) {
+	updated.Labels = merge(live.Labels, updated.Labels)
+	updated.Annotations = merge(live.Annotations, updated.Annotations)
+
+	clientConfig := webhookClientConfig(updated)
+	liveClientConfig := webhookClientConfig(live)
+	if clientConfig != nil && len(clientConfig.CABundle) == 0 && liveClientConfig != nil {
+		clientConfig.CABundle = liveClientConfig.CABundle
+	}
+}
+
+// merge returns the entries of live overridden by the ones of desired.
+func merge(live, desired map[string]string) map[string]string {
+	if len(live) == 0 {
+		return desired
+	}
+	merged := make(map[string]string, len(live)+len(desired))
+	for key, value := range live {
+		merged[key] = value
+	}
+	for key, value := range desired {
+		merged[key] = value
+	}
+	return merged
+}
+
+// webhookClientConfig returns the client configuration of the conversion
+// webhook of crd, nil when it has none.
+func webhookClientConfig(crd *apiextensionsv1.CustomResourceDefinition) *apiextensionsv1.WebhookClientConfig {
+	if conversion := crd.Spec.Conversion; conversion != nil && conversion.Webhook != nil {
+		return conversion.Webhook.ClientConfig
+	}
+	return nil
+}
+
+// removedStoredVersions returns the versions of the storedVersions of live
+// that crd does not have anymore.
+func removedStoredVersions(live, crd *apiextensionsv1.CustomResourceDefinition) []string {
+	var removed []string
+	for _, stored := range live.Status.StoredVersions {
+		if !slices.ContainsFunc(crd.Spec.Versions, func(version apiextensionsv1.CustomResourceDefinitionVersion) bool {
+			return version.Name == stored
+		}) {
+			removed = append(removed, stored)
+		}
+	}
+	return removed
+}
+
+// waitServed waits for the CRD desired to have its names accepted, to be
+// established and for its served versions to be in discovery. The conditions
+// of a CRD being upgraded are already true, discovery tells when the API
+// server serves its new versions. It fails early when its names are not
+// accepted.
+func waitServed(ctx context.Context, client clientset.Interface, desired *apiextensionsv1.CustomResourceDefinition, opts Options) error {
+	timeout := opts.Timeout
+	if timeout == 0 {
+		timeout = _defaultTimeout
+	}
+	name := desired.Name
+	var (
+		last         *apiextensionsv1.CustomResourceDefinition
+		undiscovered string
+	)
+	err := wait.PollUntilContextTimeout(ctx, _pollInterval, timeout, true, func(ctx context.Context) (bool, error) {
+		crd, err := client.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, name, metav1.GetOptions{})
+		if err != nil {
+			return false, err
+		}
+		last = crd
+		if condition := findCondition(crd, apiextensionsv1.NamesAccepted); condition != nil &&
+			condition.Status == apiextensionsv1.ConditionFalse {
+			return false, fmt.Errorf("CRD %s: names not accepted: %s", name, condition.Message)
+		}
+		if !isTrue(crd, apiextensionsv1.NamesAccepted) || !isTrue(crd, apiextensionsv1.Established) {
+			return false, nil
+		}
+		undiscovered, err = undiscoveredVersion(client, desired)
+		return undiscovered == "", err
+	})
+	if wait.Interrupted(err) {
+		conditions := "no conditions"
+		if last != nil && len(last.Status.Conditions) > 0 {
+			var reported []string
+			for _, condition := range last.Status.Conditions {
+				reported = append(reported, fmt.Sprintf("%s=%s", condition.Type, condition.Status))
+			}
+			conditions = strings.Join(reported, ", ")
+		}
+		if undiscovered != "" {
+			conditions += ", " + undiscovered + " not in discovery"
+		}
+		return fmt.Errorf("CRD %s is not served after %s (%s): %w", name, timeout, conditions, err)
+	}
+	return err
+}
+
+// undiscoveredVersion returns the first served version of crd, as group/version,
+// whose resource is not in discovery yet, or "" when they all are.
+func undiscoveredVersion(client clientset.Interface, crd *apiextensionsv1.CustomResourceDefinition) (string, error) {
+	for _, version := range crd.Spec.Versions {
+		if !version.Served {
+			continue
+		}
+		groupVersion := crd.Spec.Group + "/" + version.Name
+		resources, err := client.Discovery().ServerResourcesForGroupVersion(groupVersion)
+		if apierrors.IsNotFound(err) {
+			return groupVersion, nil
+		}
+		if err != nil {
+			return "", err
+		}
+		if !slices.ContainsFunc(resources.APIResources, func(resource metav1.APIResource) bool {
+			return resource.Name == crd.Spec.Names.Plural
+		}) {
+			return groupVersion, nil
+		}
+	}
+	return "", nil
+}
+
+func findCondition(crd *apiextensionsv1.CustomResourceDefinition, conditionType apiextensionsv1.CustomResourceDefinitionConditionType) *apiextensionsv1.CustomResourceDefinitionCondition {
+	for i := range crd.Status.Conditions {
+		if crd.Status.Conditions[i].Type == conditionType {
+			return &crd.Status.Conditions[i]
+		}
+	}
+	return nil
+}
+
+func isTrue(crd *apiextensionsv1.CustomResourceDefinition, conditionType apiextensionsv1.CustomResourceDefinitionConditionType) bool {
+	condition := findCondition(crd, conditionType)
+	return condition != nil && condition.Status == apiextensionsv1.ConditionTrue
+}
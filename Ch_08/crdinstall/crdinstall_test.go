@@ -0,0 +1,400 @@
+package crdinstall
+
+import (
+	"context"
+	"errors"
+	"maps"
+	"slices"
+	"strings"
+	"testing"
+	"testing/fstest"
+	"time"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apiextensions-apiserver/pkg/client/clientset/clientset/fake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	k8stesting "k8s.io/client-go/testing"
+)
+
+func init() {
+	_pollInterval = 10 * time.Millisecond
+}
+
+func newCRD(storage string, versions ...string) *apiextensionsv1.CustomResourceDefinition {
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "myresources.mygroup.example.com"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "mygroup.example.com",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural: "myresources",
+				Kind:   "MyResource",
+			},
+			Scope: apiextensionsv1.NamespaceScoped,
+		},
+	}
+	for _, version := range versions {
+		crd.Spec.Versions = append(crd.Spec.Versions, apiextensionsv1.CustomResourceDefinitionVersion{
+			Name:    version,
+			Served:  true,
+			Storage: version == storage,
+		})
+	}
+	return crd
+}
+
+// newClientset returns a fake clientset with the CRDs objects, that sets the
+// conditions of the CRDs created or updated to names and established,
+// maintains their storedVersions, refuses storedVersions missing from the spec
+// and lists their served versions in discovery, as the API server does. Dry
+// run updates are validated but not stored.
+func newClientset(names, established apiextensionsv1.ConditionStatus, objects ...runtime.Object) *fake.Clientset {
+	cs := fake.NewSimpleClientset(objects...)
+	discovery := cs.Discovery().(*fakediscovery.FakeDiscovery)
+	for _, object := range objects {
+		discover(discovery, object.(*apiextensionsv1.CustomResourceDefinition))
+	}
+	serve := func(action k8stesting.Action) (bool, runtime.Object, error) {
+		if action.GetSubresource() != "" {
+			return false, nil, nil
+		}
+		crd := action.(interface{ GetObject() runtime.Object }).GetObject().(*apiextensionsv1.CustomResourceDefinition)
+		crd.Status.StoredVersions = nil
+		if obj, err := cs.Tracker().Get(action.GetResource(), "", crd.Name); err == nil {
+			crd.Status.StoredVersions = obj.(*apiextensionsv1.CustomResourceDefinition).Status.StoredVersions
+		}
+		for _, stored := range crd.Status.StoredVersions {
+			if !slices.ContainsFunc(crd.Spec.Versions, func(version apiextensionsv1.CustomResourceDefinitionVersion) bool {
+				return version.Name == stored
+			}) {
+				return true, nil, apierrors.NewInvalid(apiextensionsv1.Kind("CustomResourceDefinition"), crd.Name,
+					field.ErrorList{field.Invalid(field.NewPath("status", "storedVersions"), stored, "must appear in spec.versions")})
+			}
+		}
+		if update, ok := action.(k8stesting.UpdateActionImpl); ok && len(update.UpdateOptions.DryRun) > 0 {
+			return true, crd, nil
+		}
+		for _, version := range crd.Spec.Versions {
+			if version.Storage && !slices.Contains(crd.Status.StoredVersions, version.Name) {
+				crd.Status.StoredVersions = append(crd.Status.StoredVersions, version.Name)
+			}
+		}
+		crd.Status.Conditions = []apiextensionsv1.CustomResourceDefinitionCondition{
+			{Type: apiextensionsv1.NamesAccepted, Status: names, Message: "conflicting names"},
+			{Type: apiextensionsv1.Established, Status: established},
+		}
+		discover(discovery, crd)
+		return false, nil, nil
+	}
+	cs.PrependReactor("create", "customresourcedefinitions", serve)
+	cs.PrependReactor("update", "customresourcedefinitions", serve)
+	return cs
+}
+
+// discover lists the served versions of crd in discovery.
+func discover(discovery *fakediscovery.FakeDiscovery, crd *apiextensionsv1.CustomResourceDefinition) {
+	discovery.Resources = nil
+	for _, version := range crd.Spec.Versions {
+		if version.Served {
+			discovery.Resources = append(discovery.Resources, &metav1.APIResourceList{
+				GroupVersion: crd.Spec.Group + "/" + version.Name,
+				APIResources: []metav1.APIResource{{Name: crd.Spec.Names.Plural, Kind: crd.Spec.Names.Kind}},
+			})
+		}
+	}
+}
+
+func withStoredVersions(crd *apiextensionsv1.CustomResourceDefinition, versions ...string) *apiextensionsv1.CustomResourceDefinition {
+	crd.Status.StoredVersions = versions
+	return crd
+}
+
+func TestEmbedded(t *testing.T) {
+	crds, err := Embedded()
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(crds) != 1 {
+		t.Fatalf("# of CRDs should be %d but is %d", 1, len(crds))
+	}
+	if crds[0].Name != "myresources.mygroup.example.com" {
+		t.Errorf("name of the CRD = %q", crds[0].Name)
+	}
+}
+
+func TestLoad(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		want    int
+		wantErr bool
+	}{
+		{
+			name: "case 1: several CRDs",
+			data: "apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: as.example.com\n" +
+				"---\n" +
+				"apiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: bs.example.com\n",
+			want: 2,
+		},
+		{
+			name: "case 2: empty documents",
+			data: "---\napiVersion: apiextensions.k8s.io/v1\nkind: CustomResourceDefinition\nmetadata:\n  name: as.example.com\n---\n",
+			want: 1,
+		},
+		{
+			name:    "case 3: another kind",
+			data:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: cm\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			crds, err := Load(fstest.MapFS{"crds.yaml": {Data: []byte(tt.data)}}, "*.yaml")
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if len(crds) != tt.want {
+				t.Errorf("# of CRDs should be %d but is %d", tt.want, len(crds))
+			}
+		})
+	}
+}
+
+func TestInstall(t *testing.T) {
+	tests := []struct {
+		name               string
+		live               *apiextensionsv1.CustomResourceDefinition
+		desired            *apiextensionsv1.CustomResourceDefinition
+		opts               Options
+		refuseDryRun       bool
+		refuseUpdate       bool
+		wantStoredVersions []string
+		wantVersions       []string
+		wantStatusUpdates  int
+		wantErr            bool
+	}{
+		{
+			name:               "case 1: create",
+			desired:            newCRD("v1alpha1", "v1alpha1"),
+			wantStoredVersions: []string{"v1alpha1"},
+			wantVersions:       []string{"v1alpha1"},
+		},
+		{
+			name:               "case 2: upgrade to a new storage version",
+			live:               withStoredVersions(newCRD("v1alpha1", "v1alpha1"), "v1alpha1"),
+			desired:            newCRD("v1", "v1alpha1", "v1"),
+			wantStoredVersions: []string{"v1alpha1", "v1"},
+			wantVersions:       []string{"v1alpha1", "v1"},
+		},
+		{
+			name:               "case 3: removal of a stored version refused",
+			live:               withStoredVersions(newCRD("v1", "v1alpha1", "v1"), "v1alpha1", "v1"),
+			desired:            newCRD("v1", "v1"),
+			wantStoredVersions: []string{"v1alpha1", "v1"},
+			wantVersions:       []string{"v1alpha1", "v1"},
+			wantErr:            true,
+		},
+		{
+			name:               "case 4: removal of a stored version allowed",
+			live:               withStoredVersions(newCRD("v1", "v1alpha1", "v1"), "v1alpha1", "v1"),
+			desired:            newCRD("v1", "v1"),
+			opts:               Options{AllowStoredVersionRemoval: true},
+			wantStoredVersions: []string{"v1"},
+			wantVersions:       []string{"v1"},
+			wantStatusUpdates:  1,
+		},
+		{
+			name:               "case 5: removal of a version not stored",
+			live:               withStoredVersions(newCRD("v1", "v1alpha1", "v1"), "v1"),
+			desired:            newCRD("v1", "v1"),
+			wantStoredVersions: []string{"v1"},
+			wantVersions:       []string{"v1"},
+		},
+		{
+			name:               "case 6: removal of a stored version refused by the dry run",
+			live:               withStoredVersions(newCRD("v1", "v1alpha1", "v1"), "v1alpha1", "v1"),
+			desired:            newCRD("v1", "v1"),
+			opts:               Options{AllowStoredVersionRemoval: true},
+			refuseDryRun:       true,
+			wantStoredVersions: []string{"v1alpha1", "v1"},
+			wantVersions:       []string{"v1alpha1", "v1"},
+			wantErr:            true,
+		},
+		{
+			name:               "case 7: removal of a stored version refused by the update",
+			live:               withStoredVersions(newCRD("v1", "v1alpha1", "v1"), "v1alpha1", "v1"),
+			desired:            newCRD("v1", "v1"),
+			opts:               Options{AllowStoredVersionRemoval: true},
+			refuseUpdate:       true,
+			wantStoredVersions: []string{"v1alpha1", "v1"},
+			wantVersions:       []string{"v1alpha1", "v1"},
+			wantStatusUpdates:  2,
+			wantErr:            true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			var objects []runtime.Object
+			if tt.live != nil {
+				objects = append(objects, tt.live)
+			}
+			cs := newClientset(apiextensionsv1.ConditionTrue, apiextensionsv1.ConditionTrue, objects...)
+			refused := apierrors.NewForbidden(apiextensionsv1.Resource("customresourcedefinitions"), tt.desired.Name, errors.New("refused"))
+			cs.PrependReactor("update", "customresourcedefinitions", func(action k8stesting.Action) (bool, runtime.Object, error) {
+				update := action.(k8stesting.UpdateActionImpl)
+				if update.GetSubresource() != "" {
+					return false, nil, nil
+				}
+				if dryRun := len(update.UpdateOptions.DryRun) > 0; dryRun && tt.refuseDryRun || !dryRun && tt.refuseUpdate {
+					return true, nil, refused
+				}
+				return false, nil, nil
+			})
+
+			err := Install(ctx, cs, []*apiextensionsv1.CustomResourceDefinition{tt.desired}, tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Install() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			var storedVersionsErr *StoredVersionsError
+			if tt.wantErr && !tt.refuseDryRun && !tt.refuseUpdate && !errors.As(err, &storedVersionsErr) {
+				t.Errorf("Install() error = %v, want a *StoredVersionsError", err)
+			}
+			if (tt.refuseDryRun || tt.refuseUpdate) && !errors.Is(err, refused) {
+				t.Errorf("Install() error = %v, want %v", err, refused)
+			}
+			statusUpdates := 0
+			for _, action := range cs.Actions() {
+				if action.GetVerb() == "update" && action.GetSubresource() == "status" {
+					statusUpdates++
+				}
+			}
+			if statusUpdates != tt.wantStatusUpdates {
+				t.Errorf("# of status updates should be %d but is %d", tt.wantStatusUpdates, statusUpdates)
+			}
+
+			crd, err := cs.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, tt.desired.Name, metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !slices.Equal(crd.Status.StoredVersions, tt.wantStoredVersions) {
+				t.Errorf("storedVersions = %v, want %v", crd.Status.StoredVersions, tt.wantStoredVersions)
+			}
+			var versions []string
+			for _, version := range crd.Spec.Versions {
+				versions = append(versions, version.Name)
+			}
+			if !slices.Equal(versions, tt.wantVersions) {
+				t.Errorf("versions = %v, want %v", versions, tt.wantVersions)
+			}
+		})
+	}
+}
+
+func TestInstall_injected(t *testing.T) {
+	withWebhook := func(crd *apiextensionsv1.CustomResourceDefinition, caBundle string) *apiextensionsv1.CustomResourceDefinition {
+		crd.Spec.Conversion = &apiextensionsv1.CustomResourceConversion{
+			Strategy: apiextensionsv1.WebhookConverter,
+			Webhook: &apiextensionsv1.WebhookConversion{
+				ClientConfig: &apiextensionsv1.WebhookClientConfig{
+					Service:  &apiextensionsv1.ServiceReference{Namespace: "system", Name: "webhook-service"},
+					CABundle: []byte(caBundle),
+				},
+				ConversionReviewVersions: []string{"v1"},
+			},
+		}
+		return crd
+	}
+	live := withWebhook(withStoredVersions(newCRD("v1alpha1", "v1alpha1"), "v1alpha1"), "injected CA")
+	live.Labels = map[string]string{"team": "platform", "app": "myresource"}
+	live.Annotations = map[string]string{"cert-manager.io/inject-ca-from": "system/serving-cert"}
+	desired := withWebhook(newCRD("v1", "v1alpha1", "v1"), "")
+	desired.Labels = map[string]string{"app": "myresource-crd"}
+	ctx := context.Background()
+	cs := newClientset(apiextensionsv1.ConditionTrue, apiextensionsv1.ConditionTrue, live)
+
+	if err := Install(ctx, cs, []*apiextensionsv1.CustomResourceDefinition{desired}, Options{}); err != nil {
+		t.Fatalf("Install() error = %v", err)
+	}
+
+	crd, err := cs.ApiextensionsV1().CustomResourceDefinitions().Get(ctx, desired.Name, metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if caBundle := string(crd.Spec.Conversion.Webhook.ClientConfig.CABundle); caBundle != "injected CA" {
+		t.Errorf("caBundle = %q, want the injected one", caBundle)
+	}
+	if len(crd.Spec.Versions) != 2 {
+		t.Errorf("# of versions should be %d but is %d", 2, len(crd.Spec.Versions))
+	}
+	wantLabels := map[string]string{"team": "platform", "app": "myresource-crd"}
+	if !maps.Equal(crd.Labels, wantLabels) {
+		t.Errorf("labels = %v, want %v", crd.Labels, wantLabels)
+	}
+	if !maps.Equal(crd.Annotations, live.Annotations) {
+		t.Errorf("annotations = %v, want %v", crd.Annotations, live.Annotations)
+	}
+	if desired.Labels["team"] != "" || len(desired.Spec.Conversion.Webhook.ClientConfig.CABundle) > 0 {
+		t.Errorf("Install() changed the desired CRD")
+	}
+}
+
+func TestInstall_notServed(t *testing.T) {
+	tests := []struct {
+		name        string
+		live        *apiextensionsv1.CustomResourceDefinition
+		desired     *apiextensionsv1.CustomResourceDefinition
+		names       apiextensionsv1.ConditionStatus
+		established apiextensionsv1.ConditionStatus
+		wantErr     string
+	}{
+		{
+			name:        "case 1: names not accepted",
+			desired:     newCRD("v1", "v1"),
+			names:       apiextensionsv1.ConditionFalse,
+			established: apiextensionsv1.ConditionFalse,
+			wantErr:     "names not accepted: conflicting names",
+		},
+		{
+			name:        "case 2: not established",
+			desired:     newCRD("v1", "v1"),
+			names:       apiextensionsv1.ConditionTrue,
+			established: apiextensionsv1.ConditionFalse,
+			wantErr:     "is not served after 100ms (NamesAccepted=True, Established=False)",
+		},
+		{
+			name:        "case 3: new version of an upgrade not in discovery",
+			live:        withStoredVersions(newCRD("v1alpha1", "v1alpha1"), "v1alpha1"),
+			desired:     newCRD("v1", "v1alpha1", "v1"),
+			names:       apiextensionsv1.ConditionTrue,
+			established: apiextensionsv1.ConditionTrue,
+			wantErr: "is not served after 100ms " +
+				"(NamesAccepted=True, Established=True, mygroup.example.com/v1 not in discovery)",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var objects []runtime.Object
+			if tt.live != nil {
+				objects = append(objects, tt.live)
+			}
+			cs := newClientset(tt.names, tt.established, objects...)
+			if tt.live != nil {
+				// discovery still lists the versions of the live CRD only
+				discovery := cs.Discovery().(*fakediscovery.FakeDiscovery)
+				cs.PrependReactor("get", "resource", func(k8stesting.Action) (bool, runtime.Object, error) {
+					discover(discovery, tt.live)
+					return false, nil, nil
+				})
+			}
+			err := Install(context.Background(), cs, []*apiextensionsv1.CustomResourceDefinition{tt.desired},
+				Options{Timeout: 100 * time.Millisecond})
+			if err == nil || !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("Install() error = %v, want %q", err, tt.wantErr)
+			}
+		})
+	}
+}
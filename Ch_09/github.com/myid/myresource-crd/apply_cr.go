@@ -0,0 +1,34 @@
+package main
+
+import (
+	"context"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	applyv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/applyconfigurations/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const fieldManager = "myresource-crd"
+
+// ApplyCR server-side applies the spec fields given, the empty ones are left to
+// their current owner.
+func ApplyCR(ctx context.Context, cs clientset.Interface, name, namespace, image, memory string) (cr *v1alpha1.MyResource, err error) {
+	spec := applyv1alpha1.MyResourceSpec()
+	if image != "" {
+		spec.WithImage(image)
+	}
+	if memory != "" {
+		quantity, err := resource.ParseQuantity(memory)
+		if err != nil {
+			return nil, err
+		}
+		spec.WithMemory(quantity)
+	}
+	crToApply := applyv1alpha1.MyResource(name, namespace).WithSpec(spec)
+	return cs.MygroupV1alpha1().MyResources(namespace).Apply(ctx, crToApply, metav1.ApplyOptions{
+		FieldManager: fieldManager,
+		Force:        true,
+	})
+}
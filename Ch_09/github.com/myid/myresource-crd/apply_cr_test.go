@@ -0,0 +1,87 @@
+package main
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset/fake"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	ktesting "k8s.io/client-go/testing"
+)
+
+func TestApplyCR(t *testing.T) {
+	existing := func() *v1alpha1.MyResource {
+		return &v1alpha1.MyResource{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      "myresource-crd",
+				Namespace: "default",
+			},
+			Spec: v1alpha1.MyResourceSpec{
+				Image:  "nginx",
+				Memory: resource.MustParse("1024Mi"),
+			},
+		}
+	}
+	type args struct {
+		image  string
+		memory string
+	}
+	tests := []struct {
+		name     string
+		args     args
+		wantSpec v1alpha1.MyResourceSpec
+		wantErr  bool
+	}{
+		{
+			name: "case 1: apply image only",
+			args: args{image: "nginx:1.27"},
+			wantSpec: v1alpha1.MyResourceSpec{
+				Image:  "nginx:1.27",
+				Memory: resource.MustParse("1024Mi"),
+			},
+		},
+		{
+			name: "case 2: apply memory only",
+			args: args{memory: "2Gi"},
+			wantSpec: v1alpha1.MyResourceSpec{
+				Image:  "nginx",
+				Memory: resource.MustParse("2Gi"),
+			},
+		},
+		{
+			name:    "case 3: invalid memory",
+			args:    args{memory: "2 gigs"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			clientset := fake.NewSimpleClientset(existing())
+			gotCr, err := ApplyCR(context.TODO(), clientset, "myresource-crd", "default", tt.args.image, tt.args.memory)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ApplyCR() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if gotCr.Spec.Image != tt.wantSpec.Image || gotCr.Spec.Memory.Cmp(tt.wantSpec.Memory) != 0 {
+				t.Errorf("ApplyCR() spec = %v, want %v", gotCr.Spec, tt.wantSpec)
+			}
+
+			actions := clientset.Actions()
+			if len(actions) != 1 {
+				t.Fatalf("# of actions should be %d but is %d", 1, len(actions))
+			}
+			patch, ok := actions[0].(ktesting.PatchAction)
+			if !ok || patch.GetPatchType() != types.ApplyPatchType {
+				t.Fatalf("action should be an apply patch but is %v", actions[0])
+			}
+			if patch.GetName() != "myresource-crd" {
+				t.Errorf("applied name = %q, want %q", patch.GetName(), "myresource-crd")
+			}
+		})
+	}
+}
@@ -0,0 +1,82 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	applyv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/applyconfigurations/mygroup.example.com/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newApplyCommand(o *options) *cobra.Command {
+	var (
+		filename       string
+		fieldManager   string
+		forceConflicts bool
+	)
+	printFlags := newPrintFlags("serverside-applied", false)
+	cmd := &cobra.Command{
+		Use:   "apply -f FILENAME",
+		Short: "Server-side apply MyResources from a file",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if filename == "" {
+				return errors.New("a file is required")
+			}
+			namespace, explicit, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			printer, err := printFlags.toPrinter(false, false)
+			if err != nil {
+				return err
+			}
+			docs, err := readManifests(o.streams.In, filename)
+			if err != nil {
+				return err
+			}
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+
+			for _, doc := range docs {
+				myres := &applyv1alpha1.MyResourceApplyConfiguration{}
+				if err := json.Unmarshal(doc, myres); err != nil {
+					return err
+				}
+				if myres.ObjectMetaApplyConfiguration == nil || myres.Name == nil || *myres.Name == "" {
+					return errors.New("a MyResource to apply has no name")
+				}
+				own := ""
+				if myres.Namespace != nil {
+					own = *myres.Namespace
+				}
+				own, err = objectNamespace(own, namespace, explicit)
+				if err != nil {
+					return err
+				}
+				myres.WithNamespace(own)
+
+				applied, err := cs.MygroupV1alpha1().MyResources(own).Apply(cmd.Context(), myres, metav1.ApplyOptions{
+					FieldManager: fieldManager,
+					Force:        forceConflicts,
+				})
+				if err != nil {
+					return err
+				}
+				setKind(applied)
+				if err := printer.PrintObj(applied, o.streams.Out); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "File containing the MyResources to apply, - for the standard input.")
+	cmd.Flags().StringVar(&fieldManager, "field-manager", _fieldManager, "Name of the manager of the applied fields.")
+	cmd.Flags().BoolVar(&forceConflicts, "force-conflicts", false, "Take the ownership of the fields owned by other managers.")
+	printFlags.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,92 @@
+package main
+
+import (
+	"fmt"
+
+	"github.com/myid/myresource-crd/pkg/backup"
+	"github.com/spf13/cobra"
+)
+
+func newExportCommand(o *options) *cobra.Command {
+	var selector string
+	cmd := &cobra.Command{
+		Use:   "export PATH",
+		Short: "Export the MyResources of all namespaces, or of --namespace, to a directory or to a .tar.gz tarball",
+		Args:  cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, explicit, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			opts := backup.ExportOptions{LabelSelector: selector}
+			if explicit {
+				opts.Namespace = namespace
+			}
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+
+			myresources, err := backup.Export(cmd.Context(), cs, opts)
+			if err != nil {
+				return err
+			}
+			if err := backup.Save(args[0], myresources); err != nil {
+				return err
+			}
+			fmt.Fprintf(o.streams.Out, "%d MyResources exported to %s\n", len(myresources), args[0])
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter on.")
+	return cmd
+}
+
+func newImportCommand(o *options) *cobra.Command {
+	var (
+		dryRun       bool
+		fieldManager string
+	)
+	cmd := &cobra.Command{
+		Use:   "import PATH",
+		Short: "Create or update the MyResources exported to a directory or to a .tar.gz tarball",
+		Long: "Create or update the MyResources exported to a directory or to a .tar.gz tarball, " +
+			"in their own namespace or in --namespace when given. The imported labels and annotations are " +
+			"merged into the live ones, the ones missing from the backup are kept.",
+		Args: cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, explicit, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			opts := backup.ImportOptions{DryRun: dryRun, FieldManager: fieldManager}
+			if explicit {
+				opts.Namespace = namespace
+			}
+			myresources, err := backup.Load(args[0])
+			if err != nil {
+				return err
+			}
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+
+			results, err := backup.Import(cmd.Context(), cs, myresources, opts)
+			suffix := ""
+			if dryRun {
+				suffix = " (server dry run)"
+			}
+			for _, result := range results {
+				fmt.Fprintf(o.streams.Out, "%s/%s %s%s\n", result.Namespace, result.Name, result.Action, suffix)
+				if dryRun && result.Diff != "" {
+					fmt.Fprint(o.streams.Out, result.Diff)
+				}
+			}
+			return err
+		},
+	}
+	cmd.Flags().BoolVar(&dryRun, "dry-run", false, "Show the changes, validated by the API server, without making them.")
+	cmd.Flags().StringVar(&fieldManager, "field-manager", _fieldManager, "Name of the manager of the imported fields.")
+	return cmd
+}
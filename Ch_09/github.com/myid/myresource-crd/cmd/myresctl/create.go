@@ -0,0 +1,91 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+// _fieldManager is the manager of the fields myresctl writes.
+const _fieldManager = "myresctl"
+
+func newCreateCommand(o *options) *cobra.Command {
+	var filename, image, memory string
+	printFlags := newPrintFlags("created", false)
+	cmd := &cobra.Command{
+		Use:   "create (-f FILENAME | NAME --image IMAGE --memory MEMORY)",
+		Short: "Create MyResources from a file or from flags",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, explicit, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			printer, err := printFlags.toPrinter(false, false)
+			if err != nil {
+				return err
+			}
+
+			var toCreate []*v1alpha1.MyResource
+			switch {
+			case filename != "" && len(args) > 0:
+				return errors.New("either a file or a name is expected, not both")
+			case filename != "":
+				docs, err := readManifests(o.streams.In, filename)
+				if err != nil {
+					return err
+				}
+				for _, doc := range docs {
+					myres := &v1alpha1.MyResource{}
+					if err := json.Unmarshal(doc, myres); err != nil {
+						return err
+					}
+					toCreate = append(toCreate, myres)
+				}
+			case len(args) == 1:
+				if image == "" || memory == "" {
+					return errors.New("--image and --memory are required to create a MyResource by name")
+				}
+				quantity, err := resource.ParseQuantity(memory)
+				if err != nil {
+					return err
+				}
+				toCreate = append(toCreate, &v1alpha1.MyResource{
+					ObjectMeta: metav1.ObjectMeta{Name: args[0]},
+					Spec:       v1alpha1.MyResourceSpec{Image: image, Memory: quantity},
+				})
+			default:
+				return errors.New("a file or a name is required")
+			}
+
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+			for _, myres := range toCreate {
+				if myres.Namespace, err = objectNamespace(myres.Namespace, namespace, explicit); err != nil {
+					return err
+				}
+				created, err := cs.MygroupV1alpha1().MyResources(myres.Namespace).Create(cmd.Context(), myres,
+					metav1.CreateOptions{FieldManager: _fieldManager})
+				if err != nil {
+					return err
+				}
+				setKind(created)
+				if err := printer.PrintObj(created, o.streams.Out); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "File containing the MyResources to create, - for the standard input.")
+	cmd.Flags().StringVar(&image, "image", "", "Image of the MyResource created by name.")
+	cmd.Flags().StringVar(&memory, "memory", "", "Memory of the MyResource created by name.")
+	printFlags.addFlags(cmd)
+	return cmd
+}
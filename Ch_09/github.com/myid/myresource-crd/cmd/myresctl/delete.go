@@ -0,0 +1,75 @@
+package main
+
+import (
+	"encoding/json"
+	"errors"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDeleteCommand(o *options) *cobra.Command {
+	var filename string
+	printFlags := newPrintFlags("deleted", false)
+	cmd := &cobra.Command{
+		Use:   "delete (-f FILENAME | NAME...)",
+		Short: "Delete MyResources by name or from a file",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, explicit, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			printer, err := printFlags.toPrinter(false, false)
+			if err != nil {
+				return err
+			}
+
+			var toDelete []*v1alpha1.MyResource
+			switch {
+			case filename != "" && len(args) > 0:
+				return errors.New("either a file or names are expected, not both")
+			case filename != "":
+				docs, err := readManifests(o.streams.In, filename)
+				if err != nil {
+					return err
+				}
+				for _, doc := range docs {
+					myres := &v1alpha1.MyResource{}
+					if err := json.Unmarshal(doc, myres); err != nil {
+						return err
+					}
+					toDelete = append(toDelete, myres)
+				}
+			case len(args) > 0:
+				for _, name := range args {
+					toDelete = append(toDelete, &v1alpha1.MyResource{ObjectMeta: metav1.ObjectMeta{Name: name}})
+				}
+			default:
+				return errors.New("a file or names are required")
+			}
+
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+			for _, myres := range toDelete {
+				if myres.Namespace, err = objectNamespace(myres.Namespace, namespace, explicit); err != nil {
+					return err
+				}
+				err := cs.MygroupV1alpha1().MyResources(myres.Namespace).Delete(cmd.Context(), myres.Name, metav1.DeleteOptions{})
+				if err != nil {
+					return err
+				}
+				setKind(myres)
+				if err := printer.PrintObj(myres, o.streams.Out); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	cmd.Flags().StringVarP(&filename, "filename", "f", "", "File containing the MyResources to delete, - for the standard input.")
+	printFlags.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,87 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newDescribeCommand(o *options) *cobra.Command {
+	return &cobra.Command{
+		Use:   "describe NAME...",
+		Short: "Show the details of MyResources",
+		Args:  cobra.MinimumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			namespace, _, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+			for i, name := range args {
+				myres, err := cs.MygroupV1alpha1().MyResources(namespace).Get(cmd.Context(), name, metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				if i > 0 {
+					fmt.Fprintln(o.streams.Out)
+				}
+				if err := describe(o.streams.Out, myres); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+}
+
+// describe writes the details of myres to w, aligned as kubectl describe does.
+func describe(w io.Writer, myres *v1alpha1.MyResource) error {
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	fmt.Fprintf(tw, "Name:\t%s\n", myres.Name)
+	fmt.Fprintf(tw, "Namespace:\t%s\n", myres.Namespace)
+	fmt.Fprintf(tw, "Labels:\t%s\n", formatMap(myres.Labels))
+	fmt.Fprintf(tw, "Annotations:\t%s\n", formatMap(myres.Annotations))
+	fmt.Fprintf(tw, "API Version:\t%s\n", v1alpha1.SchemeGroupVersion)
+	fmt.Fprintf(tw, "Kind:\tMyResource\n")
+	if myres.CreationTimestamp.IsZero() {
+		fmt.Fprintf(tw, "Created:\t<unknown>\n")
+	} else {
+		fmt.Fprintf(tw, "Created:\t%s (%s ago)\n", myres.CreationTimestamp.UTC().Format(time.RFC3339), age(myres.CreationTimestamp))
+	}
+	fmt.Fprintf(tw, "Generation:\t%d\n", myres.Generation)
+	fmt.Fprintf(tw, "Spec:\t\n")
+	fmt.Fprintf(tw, "  Image:\t%s\n", myres.Spec.Image)
+	fmt.Fprintf(tw, "  Memory:\t%s\n", myres.Spec.Memory.String())
+	fmt.Fprintf(tw, "Status:\t\n")
+	fmt.Fprintf(tw, "  State:\t%s\n", valueOrNone(myres.Status.State))
+	return tw.Flush()
+}
+
+func formatMap(m map[string]string) string {
+	if len(m) == 0 {
+		return "<none>"
+	}
+	pairs := make([]string, 0, len(m))
+	for k, v := range m {
+		pairs = append(pairs, k+"="+v)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+func valueOrNone(s string) string {
+	if s == "" {
+		return "<none>"
+	}
+	return s
+}
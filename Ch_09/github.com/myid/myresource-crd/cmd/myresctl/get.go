@@ -0,0 +1,77 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+func newGetCommand(o *options) *cobra.Command {
+	var (
+		allNamespaces bool
+		selector      string
+	)
+	printFlags := newPrintFlags("", true)
+	cmd := &cobra.Command{
+		Use:   "get [NAME...]",
+		Short: "Display one or many MyResources",
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if allNamespaces && len(args) > 0 {
+				return errors.New("a MyResource cannot be retrieved by name across all namespaces")
+			}
+			namespace, _, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			if allNamespaces {
+				namespace = metav1.NamespaceAll
+			}
+			printer, err := printFlags.toPrinter(allNamespaces, false)
+			if err != nil {
+				return err
+			}
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+
+			myresources := cs.MygroupV1alpha1().MyResources(namespace)
+			var obj runtime.Object
+			switch len(args) {
+			case 0:
+				list, err := myresources.List(cmd.Context(), metav1.ListOptions{LabelSelector: selector})
+				if err != nil {
+					return err
+				}
+				setKinds(list)
+				obj = list
+			case 1:
+				myres, err := myresources.Get(cmd.Context(), args[0], metav1.GetOptions{})
+				if err != nil {
+					return err
+				}
+				setKind(myres)
+				obj = myres
+			default:
+				list := &v1alpha1.MyResourceList{}
+				for _, name := range args {
+					myres, err := myresources.Get(cmd.Context(), name, metav1.GetOptions{})
+					if err != nil {
+						return err
+					}
+					list.Items = append(list.Items, *myres)
+				}
+				setKinds(list)
+				obj = list
+			}
+			return printer.PrintObj(obj, o.streams.Out)
+		},
+	}
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "List the MyResources across all namespaces.")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter on.")
+	printFlags.addFlags(cmd)
+	return cmd
+}
@@ -0,0 +1,77 @@
+// Command myresctl gets and edits MyResources with the generated clientset,
+// the way kubectl does for the built-in kinds.
+package main
+
+import (
+	"os"
+
+	"github.com/myid/myresource-crd/pkg/clientset/clientset"
+	"github.com/spf13/cobra"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+)
+
+func main() {
+	streams := genericiooptions.IOStreams{In: os.Stdin, Out: os.Stdout, ErrOut: os.Stderr}
+	if err := newRootCommand(streams, nil).Execute(); err != nil {
+		os.Exit(1)
+	}
+}
+
+// options holds what the subcommands share.
+type options struct {
+	configFlags *genericclioptions.ConfigFlags
+	streams     genericiooptions.IOStreams
+	// cs, when set, is used instead of a clientset built from the kubeconfig.
+	cs clientset.Interface
+}
+
+// newRootCommand returns the myresctl command and its subcommands, working
+// with cs or, when nil, with a clientset built from the kubeconfig flags.
+func newRootCommand(streams genericiooptions.IOStreams, cs clientset.Interface) *cobra.Command {
+	o := &options{
+		configFlags: genericclioptions.NewConfigFlags(true),
+		streams:     streams,
+		cs:          cs,
+	}
+	cmd := &cobra.Command{
+		Use:          "myresctl",
+		Short:        "myresctl gets and edits MyResources",
+		SilenceUsage: true,
+	}
+	cmd.SetIn(streams.In)
+	cmd.SetOut(streams.Out)
+	cmd.SetErr(streams.ErrOut)
+	o.configFlags.AddFlags(cmd.PersistentFlags())
+
+	cmd.AddCommand(
+		newGetCommand(o),
+		newDescribeCommand(o),
+		newCreateCommand(o),
+		newApplyCommand(o),
+		newDeleteCommand(o),
+		newWatchCommand(o),
+		newExportCommand(o),
+		newImportCommand(o),
+	)
+	return cmd
+}
+
+// clientset returns the clientset the subcommands work with.
+func (o *options) clientset() (clientset.Interface, error) {
+	if o.cs != nil {
+		return o.cs, nil
+	}
+	config, err := o.configFlags.ToRESTConfig()
+	if err != nil {
+		return nil, err
+	}
+	return clientset.NewForConfig(config)
+}
+
+// namespace returns the namespace given by --namespace, or else the one of the
+// current context of the kubeconfig. explicit tells whether it was given by
+// --namespace.
+func (o *options) namespace() (namespace string, explicit bool, err error) {
+	return o.configFlags.ToRawKubeConfigLoader().Namespace()
+}
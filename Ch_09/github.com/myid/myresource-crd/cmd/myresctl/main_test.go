@@ -0,0 +1,432 @@
+package main
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	applyv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/applyconfigurations/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/enhancedfake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/cli-runtime/pkg/genericiooptions"
+	ktesting "k8s.io/client-go/testing"
+)
+
+// _kubeconfig has team-a as the namespace of its current context.
+const _kubeconfig = `apiVersion: v1
+kind: Config
+clusters:
+- name: test
+  cluster:
+    server: https://127.0.0.1:6443
+contexts:
+- name: test
+  context:
+    cluster: test
+    namespace: team-a
+current-context: test
+`
+
+func newTestMyResource(namespace, name, image string, labels map[string]string) *v1alpha1.MyResource {
+	return &v1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: v1alpha1.MyResourceSpec{
+			Image:  image,
+			Memory: resource.MustParse("1Gi"),
+		},
+	}
+}
+
+func newApplyMyResource(name, namespace, image string) *applyv1alpha1.MyResourceApplyConfiguration {
+	return applyv1alpha1.MyResource(name, namespace).
+		WithSpec(applyv1alpha1.MyResourceSpec().
+			WithImage(image).
+			WithMemory(resource.MustParse("1Gi")))
+}
+
+func setKubeconfig(t *testing.T) {
+	t.Helper()
+	path := filepath.Join(t.TempDir(), "config")
+	if err := os.WriteFile(path, []byte(_kubeconfig), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	t.Setenv("KUBECONFIG", path)
+}
+
+func TestCommands(t *testing.T) {
+	setKubeconfig(t)
+	tests := []struct {
+		name    string
+		args    []string
+		stdin   string
+		want    []string
+		notWant []string
+		wantErr bool
+		check   func(t *testing.T, cs *enhancedfake.Clientset)
+	}{
+		{
+			name:    "case 1: get in the namespace of the kubeconfig",
+			args:    []string{"get"},
+			want:    []string{"NAME", "IMAGE", "MEMORY", "AGE", "myres1", "nginx", "1Gi"},
+			notWant: []string{"myres2", "NAMESPACE", "STATE"},
+		},
+		{
+			name: "case 2: get across all namespaces",
+			args: []string{"get", "-A"},
+			want: []string{"NAMESPACE", "team-a", "myres1", "team-b", "myres2"},
+		},
+		{
+			name: "case 3: get wide",
+			args: []string{"get", "-o", "wide"},
+			want: []string{"STATE", "Ready"},
+		},
+		{
+			name: "case 4: get by name",
+			args: []string{"get", "myres1", "-o", "name"},
+			want: []string{"myresource.mygroup.example.com/myres1\n"},
+		},
+		{
+			name: "case 5: get the names across all namespaces",
+			args: []string{"get", "-A", "-o", "name"},
+			want: []string{"myresource.mygroup.example.com/myres1\nmyresource.mygroup.example.com/myres2\n"},
+		},
+		{
+			name: "case 6: get with a jsonpath",
+			args: []string{"get", "-n", "team-b", "-o", "jsonpath={.items[*].spec.image}"},
+			want: []string{"busybox"},
+		},
+		{
+			name: "case 7: get as json",
+			args: []string{"get", "myres1", "-o", "json"},
+			want: []string{`"kind": "MyResource"`, `"apiVersion": "mygroup.example.com/v1alpha1"`, `"image": "nginx"`},
+		},
+		{
+			name:    "case 8: get as yaml with a selector",
+			args:    []string{"get", "-A", "-l", "app=web", "-o", "yaml"},
+			want:    []string{"kind: MyResource", "name: myres1"},
+			notWant: []string{"myres2"},
+		},
+		{
+			name:    "case 9: get by name across all namespaces",
+			args:    []string{"get", "-A", "myres1"},
+			wantErr: true,
+		},
+		{
+			name:    "case 10: get with an unknown output",
+			args:    []string{"get", "-o", "xml"},
+			wantErr: true,
+		},
+		{
+			name: "case 11: describe",
+			args: []string{"describe", "myres1"},
+			want: []string{"Name:         myres1", "Namespace:    team-a", "Labels:       app=web", "Image:", "Memory:", "State:"},
+		},
+		{
+			name:    "case 12: describe an unknown MyResource",
+			args:    []string{"describe", "myres3"},
+			wantErr: true,
+		},
+		{
+			name: "case 13: create by name",
+			args: []string{"create", "myres3", "--image", "nginx", "--memory", "512Mi"},
+			want: []string{"myresource.mygroup.example.com/myres3 created\n"},
+			check: func(t *testing.T, cs *enhancedfake.Clientset) {
+				myres, err := cs.MygroupV1alpha1().MyResources("team-a").Get(context.Background(), "myres3", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if myres.Spec.Memory.String() != "512Mi" {
+					t.Errorf("memory = %s, want %s", myres.Spec.Memory.String(), "512Mi")
+				}
+			},
+		},
+		{
+			name:    "case 14: create by name without memory",
+			args:    []string{"create", "myres3", "--image", "nginx"},
+			wantErr: true,
+		},
+		{
+			name: "case 15: create from the standard input",
+			args: []string{"create", "-f", "-"},
+			stdin: `apiVersion: mygroup.example.com/v1alpha1
+kind: MyResource
+metadata:
+  name: myres3
+spec:
+  image: nginx
+  memory: 512Mi
+---
+apiVersion: mygroup.example.com/v1alpha1
+kind: MyResource
+metadata:
+  name: myres4
+  namespace: team-b
+spec:
+  image: nginx
+  memory: 512Mi
+`,
+			want: []string{"myresource.mygroup.example.com/myres3 created", "myresource.mygroup.example.com/myres4 created"},
+			check: func(t *testing.T, cs *enhancedfake.Clientset) {
+				if _, err := cs.MygroupV1alpha1().MyResources("team-b").Get(context.Background(), "myres4", metav1.GetOptions{}); err != nil {
+					t.Error(err)
+				}
+			},
+		},
+		{
+			name: "case 16: create in another namespace than --namespace",
+			args: []string{"create", "-n", "team-a", "-f", "-"},
+			stdin: `{"apiVersion": "mygroup.example.com/v1alpha1", "kind": "MyResource",
+"metadata": {"name": "myres3", "namespace": "team-b"}, "spec": {"image": "nginx", "memory": "512Mi"}}`,
+			wantErr: true,
+		},
+		{
+			name: "case 17: create of another kind",
+			args: []string{"create", "-f", "-"},
+			stdin: `apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: config
+`,
+			wantErr: true,
+		},
+		{
+			name: "case 18: apply",
+			args: []string{"apply", "-f", "-"},
+			stdin: `apiVersion: mygroup.example.com/v1alpha1
+kind: MyResource
+metadata:
+  name: myres3
+spec:
+  image: nginx:1.27
+  memory: 1Gi
+`,
+			want: []string{"myresource.mygroup.example.com/myres3 serverside-applied\n"},
+			check: func(t *testing.T, cs *enhancedfake.Clientset) {
+				myres, err := cs.MygroupV1alpha1().MyResources("team-a").Get(context.Background(), "myres3", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if myres.ManagedFields[0].Manager != _fieldManager {
+					t.Errorf("manager = %q, want %q", myres.ManagedFields[0].Manager, _fieldManager)
+				}
+			},
+		},
+		{
+			name: "case 19: apply of fields owned by another manager",
+			args: []string{"apply", "-f", "-"},
+			stdin: `apiVersion: mygroup.example.com/v1alpha1
+kind: MyResource
+metadata:
+  name: myres1
+spec:
+  image: nginx:1.27
+  memory: 1Gi
+`,
+			wantErr: true,
+		},
+		{
+			name: "case 20: apply forcing the conflicts",
+			args: []string{"apply", "--force-conflicts", "-f", "-"},
+			stdin: `apiVersion: mygroup.example.com/v1alpha1
+kind: MyResource
+metadata:
+  name: myres1
+spec:
+  image: nginx:1.27
+  memory: 1Gi
+`,
+			want: []string{"myresource.mygroup.example.com/myres1 serverside-applied\n"},
+			check: func(t *testing.T, cs *enhancedfake.Clientset) {
+				myres, err := cs.MygroupV1alpha1().MyResources("team-a").Get(context.Background(), "myres1", metav1.GetOptions{})
+				if err != nil {
+					t.Fatal(err)
+				}
+				if myres.Spec.Image != "nginx:1.27" {
+					t.Errorf("image = %q, want %q", myres.Spec.Image, "nginx:1.27")
+				}
+			},
+		},
+		{
+			name: "case 21: apply without a name",
+			args: []string{"apply", "-f", "-"},
+			stdin: `apiVersion: mygroup.example.com/v1alpha1
+kind: MyResource
+spec:
+  image: nginx:1.27
+`,
+			wantErr: true,
+		},
+		{
+			name: "case 22: delete",
+			args: []string{"delete", "myres1"},
+			want: []string{"myresource.mygroup.example.com/myres1 deleted\n"},
+			check: func(t *testing.T, cs *enhancedfake.Clientset) {
+				_, err := cs.MygroupV1alpha1().MyResources("team-a").Get(context.Background(), "myres1", metav1.GetOptions{})
+				if !apierrors.IsNotFound(err) {
+					t.Errorf("error = %v, want a not found error", err)
+				}
+			},
+		},
+		{
+			name:    "case 23: delete an unknown MyResource",
+			args:    []string{"delete", "-n", "team-b", "myres1"},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			myres1 := newTestMyResource("team-a", "myres1", "nginx", map[string]string{"app": "web"})
+			myres1.Status.State = "Ready"
+			cs := enhancedfake.NewClientset(myres1, newTestMyResource("team-b", "myres2", "busybox", nil))
+			streams, in, out, _ := genericiooptions.NewTestIOStreams()
+			in.WriteString(tt.stdin)
+
+			cmd := newRootCommand(streams, cs)
+			cmd.SetArgs(tt.args)
+			err := cmd.Execute()
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Execute() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			for _, want := range tt.want {
+				if !strings.Contains(out.String(), want) {
+					t.Errorf("output %q does not contain %q", out.String(), want)
+				}
+			}
+			for _, notWant := range tt.notWant {
+				if strings.Contains(out.String(), notWant) {
+					t.Errorf("output %q contains %q", out.String(), notWant)
+				}
+			}
+			if tt.check != nil {
+				tt.check(t, cs)
+			}
+		})
+	}
+}
+
+// syncBuffer is a bytes.Buffer safe to read while the command writes to it.
+type syncBuffer struct {
+	mu  sync.Mutex
+	buf bytes.Buffer
+}
+
+func (b *syncBuffer) Write(p []byte) (int, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.Write(p)
+}
+
+func (b *syncBuffer) String() string {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.buf.String()
+}
+
+func TestWatchCommand(t *testing.T) {
+	setKubeconfig(t)
+	cs := enhancedfake.NewClientset(newTestMyResource("team-a", "myres1", "nginx", nil))
+	watchStarted := make(chan struct{})
+	cs.PrependWatchReactor("myresources", func(action ktesting.Action) (bool, watch.Interface, error) {
+		close(watchStarted)
+		return false, nil, nil
+	})
+	out := &syncBuffer{}
+	streams := genericiooptions.IOStreams{In: &bytes.Buffer{}, Out: out, ErrOut: &bytes.Buffer{}}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	cmd := newRootCommand(streams, cs)
+	cmd.SetArgs([]string{"watch", "myres1"})
+	done := make(chan error)
+	go func() {
+		done <- cmd.ExecuteContext(ctx)
+	}()
+
+	select {
+	case <-watchStarted:
+	case err := <-done:
+		t.Fatalf("ExecuteContext() error = %v before watching", err)
+	}
+	if _, err := cs.MygroupV1alpha1().MyResources("team-a").Create(ctx,
+		newTestMyResource("team-a", "myres2", "nginx", nil), metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := cs.MygroupV1alpha1().MyResources("team-a").Apply(ctx,
+		newApplyMyResource("myres1", "team-a", "nginx:1.27"), metav1.ApplyOptions{FieldManager: "test", Force: true}); err != nil {
+		t.Fatal(err)
+	}
+
+	deadline := time.After(5 * time.Second)
+	for !strings.Contains(out.String(), "nginx:1.27") {
+		select {
+		case <-deadline:
+			t.Fatalf("output %q does not contain the update", out.String())
+		case <-time.After(10 * time.Millisecond):
+		}
+	}
+	cancel()
+	if err := <-done; err != nil {
+		t.Errorf("ExecuteContext() error = %v", err)
+	}
+
+	got := out.String()
+	if n := strings.Count(got, "NAME"); n != 1 {
+		t.Errorf("# of headers should be %d but is %d in %q", 1, n, got)
+	}
+	if strings.Contains(got, "myres2") {
+		t.Errorf("output %q contains another MyResource", got)
+	}
+}
+
+func TestExportImportCommands(t *testing.T) {
+	setKubeconfig(t)
+	path := filepath.Join(t.TempDir(), "backup.tar.gz")
+	run := func(cs *enhancedfake.Clientset, args ...string) string {
+		t.Helper()
+		streams, _, out, _ := genericiooptions.NewTestIOStreams()
+		cmd := newRootCommand(streams, cs)
+		cmd.SetArgs(args)
+		if err := cmd.Execute(); err != nil {
+			t.Fatalf("%v: %v", args, err)
+		}
+		return out.String()
+	}
+
+	source := enhancedfake.NewClientset(
+		newTestMyResource("team-a", "myres1", "nginx:1.27", nil),
+		newTestMyResource("team-b", "myres2", "busybox", nil),
+	)
+	if got := run(source, "export", path); got != "2 MyResources exported to "+path+"\n" {
+		t.Errorf("export output = %q", got)
+	}
+
+	target := enhancedfake.NewClientset(newTestMyResource("team-a", "myres1", "nginx", nil))
+	got := run(target, "import", "--dry-run", path)
+	for _, want := range []string{
+		"team-a/myres1 configured (server dry run)", "-  image: nginx\n", "+  image: nginx:1.27\n",
+		"team-b/myres2 created (server dry run)", "+  image: busybox\n",
+	} {
+		if !strings.Contains(got, want) {
+			t.Errorf("import --dry-run output %q does not contain %q", got, want)
+		}
+	}
+	if _, err := target.MygroupV1alpha1().MyResources("team-b").Get(context.Background(), "myres2", metav1.GetOptions{}); !apierrors.IsNotFound(err) {
+		t.Errorf("import --dry-run created myres2: %v", err)
+	}
+
+	if got := run(target, "import", path); got != "team-a/myres1 configured\nteam-b/myres2 created\n" {
+		t.Errorf("import output = %q", got)
+	}
+	if got := run(target, "import", "-n", "team-c", path); got != "team-c/myres1 created\nteam-c/myres2 created\n" {
+		t.Errorf("import -n output = %q", got)
+	}
+}
@@ -0,0 +1,68 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	utilyaml "k8s.io/apimachinery/pkg/util/yaml"
+)
+
+// readManifests reads the MyResources of filename, or of in when filename is
+// "-", and returns them as JSON documents. The file can be JSON, or YAML with
+// several documents.
+func readManifests(in io.Reader, filename string) ([][]byte, error) {
+	r := in
+	if filename != "-" {
+		f, err := os.Open(filename)
+		if err != nil {
+			return nil, err
+		}
+		defer f.Close()
+		r = f
+	}
+
+	var docs [][]byte
+	decoder := utilyaml.NewYAMLOrJSONDecoder(r, 4096)
+	for {
+		var doc json.RawMessage
+		err := decoder.Decode(&doc)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if len(doc) == 0 || string(doc) == "null" {
+			continue
+		}
+		typeMeta := metav1.TypeMeta{}
+		if err := json.Unmarshal(doc, &typeMeta); err != nil {
+			return nil, fmt.Errorf("%s: %w", filename, err)
+		}
+		if gvk := typeMeta.GroupVersionKind(); gvk != v1alpha1.SchemeGroupVersion.WithKind("MyResource") {
+			return nil, fmt.Errorf("%s: %q of apiVersion %q is not a MyResource", filename, gvk.Kind, typeMeta.APIVersion)
+		}
+		docs = append(docs, doc)
+	}
+	if len(docs) == 0 {
+		return nil, fmt.Errorf("%s: no MyResource found", filename)
+	}
+	return docs, nil
+}
+
+// objectNamespace returns the namespace to write an object of a manifest to:
+// its own, or namespace when it has none. As for kubectl, the object must not
+// be in another namespace than one given explicitly with --namespace.
+func objectNamespace(own, namespace string, explicit bool) (string, error) {
+	if own == "" {
+		return namespace, nil
+	}
+	if explicit && own != namespace {
+		return "", fmt.Errorf("the namespace of the object %q does not match the namespace %q", own, namespace)
+	}
+	return own, nil
+}
@@ -0,0 +1,147 @@
+package main
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset/scheme"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/util/duration"
+	"k8s.io/cli-runtime/pkg/genericclioptions"
+	"k8s.io/cli-runtime/pkg/printers"
+)
+
+// _columns are the additionalPrinterColumns of the MyResource CRD, followed
+// by the wide ones.
+var _columns = []metav1.TableColumnDefinition{
+	{Name: "Name", Type: "string", Format: "name"},
+	{Name: "Image", Type: "string"},
+	{Name: "Memory", Type: "string"},
+	{Name: "Age", Type: "date"},
+	{Name: "State", Type: "string", Priority: 1},
+}
+
+// printFlags binds --output and returns the printer it selects.
+type printFlags struct {
+	*genericclioptions.PrintFlags
+	// tables tells whether no output format, and wide, print a table as get
+	// does, instead of the name of the object and the operation done on it.
+	tables bool
+}
+
+func newPrintFlags(operation string, tables bool) *printFlags {
+	return &printFlags{
+		PrintFlags: genericclioptions.NewPrintFlags(operation).WithTypeSetter(scheme.Scheme),
+		tables:     tables,
+	}
+}
+
+func (f *printFlags) addFlags(cmd *cobra.Command) {
+	f.JSONYamlPrintFlags.AddFlags(cmd)
+	f.TemplatePrinterFlags.AddFlags(cmd)
+	formats := f.AllowedFormats()
+	if f.tables {
+		formats = append(formats, "wide")
+	}
+	cmd.Flags().StringVarP(f.OutputFormat, "output", "o", "",
+		fmt.Sprintf("Output format. One of: (%s).", strings.Join(formats, ", ")))
+	f.OutputFlagSpecified = func() bool {
+		return cmd.Flag("output").Changed
+	}
+}
+
+// toPrinter returns the printer of the output format. Tables get a namespace
+// column when withNamespace is true, and no headers when noHeaders is true.
+func (f *printFlags) toPrinter(withNamespace, noHeaders bool) (printers.ResourcePrinter, error) {
+	if output := *f.OutputFormat; f.tables && (output == "" || output == "wide") {
+		return &tablePrinter{printers.NewTablePrinter(printers.PrintOptions{
+			NoHeaders:     noHeaders,
+			WithNamespace: withNamespace,
+			Wide:          output == "wide",
+		})}, nil
+	}
+	printer, err := f.PrintFlags.ToPrinter()
+	if err != nil {
+		return nil, err
+	}
+	if *f.OutputFormat == "name" {
+		// the name printer does not accept lists, their items are printed
+		return &itemsPrinter{printer}, nil
+	}
+	return printer, nil
+}
+
+// itemsPrinter prints the items of MyResourceLists one at a time.
+type itemsPrinter struct {
+	delegate printers.ResourcePrinter
+}
+
+func (p *itemsPrinter) PrintObj(obj runtime.Object, w io.Writer) error {
+	list, ok := obj.(*v1alpha1.MyResourceList)
+	if !ok {
+		return p.delegate.PrintObj(obj, w)
+	}
+	for i := range list.Items {
+		if err := p.delegate.PrintObj(&list.Items[i], w); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// tablePrinter prints MyResources and MyResourceLists as tables.
+type tablePrinter struct {
+	delegate printers.ResourcePrinter
+}
+
+func (p *tablePrinter) PrintObj(obj runtime.Object, w io.Writer) error {
+	table := &metav1.Table{ColumnDefinitions: _columns}
+	switch obj := obj.(type) {
+	case *v1alpha1.MyResource:
+		table.Rows = append(table.Rows, toRow(obj))
+	case *v1alpha1.MyResourceList:
+		for i := range obj.Items {
+			table.Rows = append(table.Rows, toRow(&obj.Items[i]))
+		}
+	default:
+		return fmt.Errorf("cannot print %T as a table", obj)
+	}
+	return p.delegate.PrintObj(table, w)
+}
+
+func toRow(myres *v1alpha1.MyResource) metav1.TableRow {
+	return metav1.TableRow{
+		Cells: []interface{}{
+			myres.Name,
+			myres.Spec.Image,
+			myres.Spec.Memory.String(),
+			age(myres.CreationTimestamp),
+			myres.Status.State,
+		},
+		Object: runtime.RawExtension{Object: myres},
+	}
+}
+
+func age(created metav1.Time) string {
+	if created.IsZero() {
+		return "<unknown>"
+	}
+	return duration.HumanDuration(time.Since(created.Time))
+}
+
+// setKinds sets the apiVersion and kind of the items of list, which the
+// clientset leaves empty, for the printers that need them.
+func setKinds(list *v1alpha1.MyResourceList) {
+	for i := range list.Items {
+		setKind(&list.Items[i])
+	}
+}
+
+func setKind(myres *v1alpha1.MyResource) {
+	myres.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("MyResource"))
+}
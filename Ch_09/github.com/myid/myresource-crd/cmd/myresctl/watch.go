@@ -0,0 +1,103 @@
+package main
+
+import (
+	"errors"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/spf13/cobra"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/fields"
+	"k8s.io/apimachinery/pkg/watch"
+)
+
+func newWatchCommand(o *options) *cobra.Command {
+	var (
+		allNamespaces bool
+		selector      string
+	)
+	printFlags := newPrintFlags("", true)
+	cmd := &cobra.Command{
+		Use:   "watch [NAME]",
+		Short: "Display MyResources, then their changes as they happen",
+		Args:  cobra.MaximumNArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if allNamespaces && len(args) > 0 {
+				return errors.New("a MyResource cannot be watched by name across all namespaces")
+			}
+			namespace, _, err := o.namespace()
+			if err != nil {
+				return err
+			}
+			if allNamespaces {
+				namespace = metav1.NamespaceAll
+			}
+			printer, err := printFlags.toPrinter(allNamespaces, false)
+			if err != nil {
+				return err
+			}
+			// the headers are printed once, with the current MyResources
+			eventPrinter, err := printFlags.toPrinter(allNamespaces, true)
+			if err != nil {
+				return err
+			}
+			cs, err := o.clientset()
+			if err != nil {
+				return err
+			}
+
+			listOptions := metav1.ListOptions{LabelSelector: selector}
+			name := ""
+			if len(args) == 1 {
+				name = args[0]
+				listOptions.FieldSelector = fields.OneTermEqualSelector("metadata.name", name).String()
+			}
+			myresources := cs.MygroupV1alpha1().MyResources(namespace)
+			list, err := myresources.List(cmd.Context(), listOptions)
+			if err != nil {
+				return err
+			}
+			current := &v1alpha1.MyResourceList{}
+			for _, myres := range list.Items {
+				if name == "" || myres.Name == name {
+					current.Items = append(current.Items, myres)
+				}
+			}
+			setKinds(current)
+			if err := printer.PrintObj(current, o.streams.Out); err != nil {
+				return err
+			}
+
+			listOptions.ResourceVersion = list.ResourceVersion
+			w, err := myresources.Watch(cmd.Context(), listOptions)
+			if err != nil {
+				return err
+			}
+			defer w.Stop()
+			for {
+				select {
+				case <-cmd.Context().Done():
+					return nil
+				case event, ok := <-w.ResultChan():
+					if !ok {
+						return errors.New("the watch has been closed by the server")
+					}
+					if event.Type == watch.Error {
+						return errors.New("the watch failed")
+					}
+					myres, ok := event.Object.(*v1alpha1.MyResource)
+					if !ok || (name != "" && myres.Name != name) {
+						continue
+					}
+					setKind(myres)
+					if err := eventPrinter.PrintObj(myres, o.streams.Out); err != nil {
+						return err
+					}
+				}
+			}
+		},
+	}
+	cmd.Flags().BoolVarP(&allNamespaces, "all-namespaces", "A", false, "Watch the MyResources across all namespaces.")
+	cmd.Flags().StringVarP(&selector, "selector", "l", "", "Label selector to filter on.")
+	printFlags.addFlags(cmd)
+	return cmd
+}
@@ -3,12 +3,12 @@ package main
 import (
 	"context"
 	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
-	"github.com/myid/myresource-crd/pkg/clientset/clientset/fake"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
 
-func CreateCR(ctx context.Context, clientset *fake.Clientset, name, namespace, image, memory string) (cr *v1alpha1.MyResource, err error) {
+func CreateCR(ctx context.Context, cs clientset.Interface, name, namespace, image, memory string) (cr *v1alpha1.MyResource, err error) {
 	crToCreate := &v1alpha1.MyResource{
 		TypeMeta: metav1.TypeMeta{
 			APIVersion: "mygroup.example.com/v1alpha1",
@@ -22,5 +22,5 @@ func CreateCR(ctx context.Context, clientset *fake.Clientset, name, namespace, i
 			Memory: resource.MustParse(memory),
 		},
 	}
-	return clientset.MygroupV1alpha1().MyResources(namespace).Create(ctx, crToCreate, metav1.CreateOptions{})
+	return cs.MygroupV1alpha1().MyResources(namespace).Create(ctx, crToCreate, metav1.CreateOptions{})
 }
@@ -5,6 +5,8 @@ import (
 	"errors"
 	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
 	"github.com/myid/myresource-crd/pkg/clientset/clientset/fake"
+	"github.com/myid/myresource-crd/pkg/clientset/enhancedfake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"reflect"
@@ -66,3 +68,22 @@ func TestCreateCR(t *testing.T) {
 		})
 	}
 }
+
+func TestCreateCRWithEnhancedFake(t *testing.T) {
+	clientset := enhancedfake.NewClientset()
+	ctx := context.TODO()
+
+	cr, err := CreateCR(ctx, clientset, "myresource-crd", "default", "myresource-crd", "1024Mi")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if cr.Generation != 1 || cr.ResourceVersion == "" || cr.UID == "" {
+		t.Errorf("CreateCR() generation = %d, resourceVersion = %q, uid = %q, want them set by the server",
+			cr.Generation, cr.ResourceVersion, cr.UID)
+	}
+
+	_, err = CreateCR(ctx, clientset, "myresource-crd", "default", "myresource-crd", "1024Mi")
+	if !apierrors.IsAlreadyExists(err) {
+		t.Errorf("CreateCR() error = %v, want AlreadyExists", err)
+	}
+}
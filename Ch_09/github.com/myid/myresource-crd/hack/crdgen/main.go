@@ -0,0 +1,95 @@
+// Command crdgen generates the CustomResourceDefinition of MyResource from the
+// kubebuilder markers of its Go types. Run it from the root of the module:
+//
+//	go run ./hack/crdgen
+//
+// The crdinstall package of chapter 8, in another module, embeds a copy of the
+// CRD: make crd regenerates the CRD and refreshes the copy.
+package main
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"runtime/debug"
+
+	"sigs.k8s.io/controller-tools/pkg/crd"
+	"sigs.k8s.io/controller-tools/pkg/genall"
+	"sigs.k8s.io/controller-tools/pkg/loader"
+)
+
+const (
+	// _apis are the packages of the Go types.
+	_apis = "./pkg/apis/..."
+	// _crd is the committed CRD, copied to the installer of chapter 8.
+	_crd = "manifests/myresources.mygroup.example.com.yaml"
+)
+
+func main() {
+	out := flag.String("out", _crd, "file to write the CRD to, - for the standard output")
+	flag.Parse()
+
+	data, err := generate(_apis)
+	if err != nil {
+		log.Fatal(err)
+	}
+	if *out == "-" {
+		_, err = os.Stdout.Write(data)
+	} else {
+		err = os.WriteFile(*out, data, 0o644)
+	}
+	if err != nil {
+		log.Fatal(err)
+	}
+}
+
+// generate returns the YAML of the CRDs of the Go types of the packages
+// matched by paths.
+func generate(paths ...string) ([]byte, error) {
+	generator := genall.Generator(crd.Generator{})
+	runtime, err := genall.Generators{&generator}.ForRoots(paths...)
+	if err != nil {
+		return nil, err
+	}
+	output := &bufferOutput{}
+	runtime.OutputRules = genall.OutputRules{Default: output}
+	errs := &bytes.Buffer{}
+	runtime.ErrorWriter = errs
+	if runtime.Run() {
+		return nil, fmt.Errorf("generating the CRD of %v failed: %s", paths, errs.String())
+	}
+	// the generator annotates the CRD with the version of the main module,
+	// which is not controller-tools when it is used as a library
+	return bytes.Replace(output.Bytes(),
+		[]byte("controller-gen.kubebuilder.io/version: (devel)"),
+		[]byte("controller-gen.kubebuilder.io/version: "+controllerToolsVersion()), 1), nil
+}
+
+// controllerToolsVersion returns the version of the controller-tools module
+// this command is built with.
+func controllerToolsVersion() string {
+	if info, ok := debug.ReadBuildInfo(); ok {
+		for _, dep := range info.Deps {
+			if dep.Path == "sigs.k8s.io/controller-tools" {
+				return dep.Version
+			}
+		}
+	}
+	return "(devel)"
+}
+
+// bufferOutput collects the artifacts generated, in order.
+type bufferOutput struct {
+	bytes.Buffer
+}
+
+func (o *bufferOutput) Open(*loader.Package, string) (io.WriteCloser, error) {
+	return o, nil
+}
+
+func (o *bufferOutput) Close() error {
+	return nil
+}
@@ -0,0 +1,28 @@
+package main
+
+import (
+	"bytes"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/google/go-cmp/cmp"
+)
+
+// TestCRDIsUpToDate fails when the types have changed since the committed CRD
+// was generated. Run go run ./hack/crdgen to regenerate it.
+func TestCRDIsUpToDate(t *testing.T) {
+	want, err := generate(filepath.Join("..", "..", _apis))
+	if err != nil {
+		t.Fatal(err)
+	}
+	got, err := os.ReadFile(filepath.Join("..", "..", filepath.FromSlash(_crd)))
+	if err != nil {
+		t.Fatal(err)
+	}
+	// the committed CRD may have been checked out with CRLF line endings
+	got = bytes.ReplaceAll(got, []byte("\r\n"), []byte("\n"))
+	if diff := cmp.Diff(string(want), string(got)); diff != "" {
+		t.Errorf("the CRD is stale, run go run ./hack/crdgen (-generated +committed):\n%s", diff)
+	}
+}
@@ -0,0 +1,86 @@
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset/fake"
+	"github.com/myid/myresource-crd/pkg/clientset/informers/externalversions"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/apimachinery/pkg/watch"
+	ktesting "k8s.io/client-go/testing"
+	"k8s.io/client-go/tools/cache"
+)
+
+func TestMyResourceInformer(t *testing.T) {
+	clientset := fake.NewSimpleClientset(
+		&v1alpha1.MyResource{ObjectMeta: metav1.ObjectMeta{Name: "a", Namespace: "default"}},
+		&v1alpha1.MyResource{ObjectMeta: metav1.ObjectMeta{Name: "b", Namespace: "other"}},
+	)
+	// the fake clientset drops the events sent before the watch is set up
+	watcherStarted := make(chan struct{})
+	clientset.PrependWatchReactor("*", func(action ktesting.Action) (bool, watch.Interface, error) {
+		w, err := clientset.Tracker().Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+		close(watcherStarted)
+		return true, w, nil
+	})
+	factory := externalversions.NewSharedInformerFactory(clientset, time.Minute)
+	informer := factory.Mygroup().V1alpha1().MyResources()
+	lister := informer.Lister()
+
+	added := make(chan string, 10)
+	_, err := informer.Informer().AddEventHandler(cache.ResourceEventHandlerFuncs{
+		AddFunc: func(obj interface{}) {
+			added <- obj.(*v1alpha1.MyResource).Name
+		},
+	})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	factory.Start(ctx.Done())
+	for typ, synced := range factory.WaitForCacheSync(ctx.Done()) {
+		if !synced {
+			t.Fatalf("informer for %v not synced", typ)
+		}
+	}
+
+	all, err := lister.List(labels.Everything())
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(all) != 2 {
+		t.Errorf("# of myresources should be %d but is %d", 2, len(all))
+	}
+	if _, err := lister.MyResources("default").Get("b"); err == nil {
+		t.Error("Error should happen, b is not in the default namespace")
+	}
+
+	<-watcherStarted
+	_, err = clientset.MygroupV1alpha1().MyResources("default").Create(ctx,
+		&v1alpha1.MyResource{ObjectMeta: metav1.ObjectMeta{Name: "c", Namespace: "default"}},
+		metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	seen := map[string]bool{}
+	for len(seen) < 3 {
+		select {
+		case name := <-added:
+			seen[name] = true
+		case <-time.After(5 * time.Second):
+			t.Fatalf("add events received for %v, want a, b and c", seen)
+		}
+	}
+	if _, err := lister.MyResources("default").Get("c"); err != nil {
+		t.Error(err)
+	}
+}
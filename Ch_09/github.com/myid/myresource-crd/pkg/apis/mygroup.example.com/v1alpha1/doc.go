@@ -1,3 +1,4 @@
 // pkg/apis/mygroup.example.com/v1alpha1/doc.go
 // +k8s:deepcopy-gen=package
+// +groupName=mygroup.example.com
 package v1alpha1
@@ -7,24 +7,34 @@ import (
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
 // +genclient
+// +kubebuilder:object:root=true
+// +kubebuilder:resource:shortName=my;myres,categories=all
+// +kubebuilder:subresource:status
+// +kubebuilder:printcolumn:name="image",type=string,JSONPath=".spec.image"
+// +kubebuilder:printcolumn:name="memory",type=string,JSONPath=".spec.memory"
+// +kubebuilder:printcolumn:name="age",type=date,JSONPath=".metadata.creationTimestamp"
 type MyResource struct {
 	metav1.TypeMeta   `json:",inline"`
 	metav1.ObjectMeta `json:"metadata,omitempty"`
 
-	Spec   MyResourceSpec   `json:"spec"`
+	Spec MyResourceSpec `json:"spec"`
+	// +optional
 	Status MyResourceStatus `json:"status"`
 }
 
 type MyResourceSpec struct {
+	// +kubebuilder:validation:MinLength=1
 	Image  string            `json:"image"`
 	Memory resource.Quantity `json:"memory"`
 }
 
 type MyResourceStatus struct {
+	// +optional
 	State string `json:"state"`
 }
 
 // +k8s:deepcopy-gen:interfaces=k8s.io/apimachinery/pkg/runtime.Object
+// +kubebuilder:object:root=true
 type MyResourceList struct {
 	metav1.TypeMeta `json:",inline"`
 	metav1.ListMeta `json:"metadata,omitempty"`
@@ -0,0 +1,166 @@
+package backup
+
+import (
+	"archive/tar"
+	"compress/gzip"
+	"errors"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+)
+
+// IsTarball tells whether the backup at path is a gzipped tarball, from its
+// extension, rather than a directory.
+func IsTarball(path string) bool {
+	return strings.HasSuffix(path, ".tar.gz") || strings.HasSuffix(path, ".tgz")
+}
+
+// Save writes myresources to the directory or to the gzipped tarball at path,
+// as one YAML file per MyResource, named namespace/name.yaml.
+func Save(path string, myresources []*v1alpha1.MyResource) error {
+	if !IsTarball(path) {
+		return WriteDir(path, myresources)
+	}
+	f, err := os.Create(path)
+	if err != nil {
+		return err
+	}
+	if err := WriteTarball(f, myresources); err != nil {
+		f.Close()
+		return err
+	}
+	return f.Close()
+}
+
+// Load reads the MyResources of the directory or of the gzipped tarball at
+// path.
+func Load(path string) ([]*v1alpha1.MyResource, error) {
+	info, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return ReadDir(path)
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	return ReadTarball(f)
+}
+
+// WriteDir writes myresources to dir, as namespace/name.yaml files.
+func WriteDir(dir string, myresources []*v1alpha1.MyResource) error {
+	for _, myres := range myresources {
+		data, err := Marshal(myres)
+		if err != nil {
+			return err
+		}
+		file := filepath.Join(dir, filepath.FromSlash(fileName(myres)))
+		if err := os.MkdirAll(filepath.Dir(file), 0o755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(file, data, 0o644); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// ReadDir reads the MyResources of the YAML files of dir and of its
+// subdirectories.
+func ReadDir(dir string) ([]*v1alpha1.MyResource, error) {
+	var myresources []*v1alpha1.MyResource
+	err := filepath.WalkDir(dir, func(file string, entry fs.DirEntry, err error) error {
+		if err != nil || entry.IsDir() || !isYAML(file) {
+			return err
+		}
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return err
+		}
+		myres, err := Unmarshal(data)
+		if err != nil {
+			return fmt.Errorf("%s: %w", file, err)
+		}
+		myresources = append(myresources, myres)
+		return nil
+	})
+	return myresources, err
+}
+
+// WriteTarball writes myresources to w, as a gzipped tarball of
+// namespace/name.yaml files.
+func WriteTarball(w io.Writer, myresources []*v1alpha1.MyResource) error {
+	gz := gzip.NewWriter(w)
+	tw := tar.NewWriter(gz)
+	for _, myres := range myresources {
+		data, err := Marshal(myres)
+		if err != nil {
+			return err
+		}
+		if err := tw.WriteHeader(&tar.Header{
+			Name:     fileName(myres),
+			Mode:     0o644,
+			Size:     int64(len(data)),
+			Typeflag: tar.TypeReg,
+		}); err != nil {
+			return err
+		}
+		if _, err := tw.Write(data); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gz.Close()
+}
+
+// ReadTarball reads the MyResources of the YAML files of the gzipped tarball r.
+func ReadTarball(r io.Reader) ([]*v1alpha1.MyResource, error) {
+	gz, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, err
+	}
+	defer gz.Close()
+
+	var myresources []*v1alpha1.MyResource
+	tr := tar.NewReader(gz)
+	for {
+		header, err := tr.Next()
+		if errors.Is(err, io.EOF) {
+			return myresources, nil
+		}
+		if err != nil {
+			return nil, err
+		}
+		if header.Typeflag != tar.TypeReg || !isYAML(header.Name) {
+			continue
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, err
+		}
+		myres, err := Unmarshal(data)
+		if err != nil {
+			return nil, fmt.Errorf("%s: %w", header.Name, err)
+		}
+		myresources = append(myresources, myres)
+	}
+}
+
+func fileName(myres *v1alpha1.MyResource) string {
+	return path.Join(myres.Namespace, myres.Name+".yaml")
+}
+
+func isYAML(name string) bool {
+	return strings.HasSuffix(name, ".yaml") || strings.HasSuffix(name, ".yml")
+}
@@ -0,0 +1,250 @@
+// Package backup exports MyResources to YAML files and imports them back, to
+// move them between clusters.
+package backup
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset"
+	"github.com/pmezard/go-difflib/difflib"
+	"k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/yaml"
+)
+
+// _lastAppliedAnnotation is set by client-side kubectl apply, it refers to
+// the object of the source cluster.
+const _lastAppliedAnnotation = "kubectl.kubernetes.io/last-applied-configuration"
+
+// ExportOptions selects the MyResources to export.
+type ExportOptions struct {
+	// Namespace, when set, restricts the export to a namespace.
+	Namespace string
+	// LabelSelector, when set, restricts the export to the MyResources it
+	// matches.
+	LabelSelector string
+}
+
+// Export returns the MyResources selected by opts, sorted by namespace and
+// name, with the metadata populated by the API server stripped.
+func Export(ctx context.Context, cs clientset.Interface, opts ExportOptions) ([]*v1alpha1.MyResource, error) {
+	list, err := cs.MygroupV1alpha1().MyResources(opts.Namespace).List(ctx, metav1.ListOptions{
+		LabelSelector: opts.LabelSelector,
+	})
+	if err != nil {
+		return nil, err
+	}
+	exported := make([]*v1alpha1.MyResource, 0, len(list.Items))
+	for i := range list.Items {
+		exported = append(exported, Strip(&list.Items[i]))
+	}
+	sort.Slice(exported, func(i, j int) bool {
+		if exported[i].Namespace != exported[j].Namespace {
+			return exported[i].Namespace < exported[j].Namespace
+		}
+		return exported[i].Name < exported[j].Name
+	})
+	return exported, nil
+}
+
+// Strip returns a copy of myres without its status and the metadata that
+// only makes sense in its cluster, so that it can be created in another one.
+func Strip(myres *v1alpha1.MyResource) *v1alpha1.MyResource {
+	stripped := &v1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:        myres.Name,
+			Namespace:   myres.Namespace,
+			Labels:      myres.Labels,
+			Annotations: myres.Annotations,
+		},
+		Spec: myres.Spec,
+	}
+	stripped = stripped.DeepCopy()
+	stripped.SetGroupVersionKind(v1alpha1.SchemeGroupVersion.WithKind("MyResource"))
+	delete(stripped.Annotations, _lastAppliedAnnotation)
+	if len(stripped.Annotations) == 0 {
+		stripped.Annotations = nil
+	}
+	return stripped
+}
+
+// Marshal returns the YAML of myres, without the empty creationTimestamp and
+// status of a stripped MyResource.
+func Marshal(myres *v1alpha1.MyResource) ([]byte, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(myres)
+	if err != nil {
+		return nil, err
+	}
+	if myres.CreationTimestamp.IsZero() {
+		unstructured.RemoveNestedField(obj, "metadata", "creationTimestamp")
+	}
+	if myres.Status == (v1alpha1.MyResourceStatus{}) {
+		delete(obj, "status")
+	}
+	return yaml.Marshal(obj)
+}
+
+// Unmarshal returns the MyResource of the YAML or JSON data.
+func Unmarshal(data []byte) (*v1alpha1.MyResource, error) {
+	myres := &v1alpha1.MyResource{}
+	if err := yaml.UnmarshalStrict(data, myres); err != nil {
+		return nil, err
+	}
+	if gvk := myres.GroupVersionKind(); gvk != v1alpha1.SchemeGroupVersion.WithKind("MyResource") {
+		return nil, fmt.Errorf("%q of apiVersion %q is not a MyResource", gvk.Kind, myres.APIVersion)
+	}
+	if myres.Name == "" {
+		return nil, fmt.Errorf("a MyResource has no name")
+	}
+	return myres, nil
+}
+
+// Action is what Import does with a MyResource.
+type Action string
+
+const (
+	// Created is for a MyResource that did not exist.
+	Created Action = "created"
+	// Updated is for a MyResource that existed with another spec, labels or
+	// annotations.
+	Updated Action = "configured"
+	// Unchanged is for a MyResource that existed as imported.
+	Unchanged Action = "unchanged"
+)
+
+// ImportOptions tunes Import.
+type ImportOptions struct {
+	// Namespace, when set, is the namespace the MyResources are imported to,
+	// instead of their own.
+	Namespace string
+	// DryRun, when true, makes the API server validate the changes without
+	// persisting them.
+	DryRun bool
+	// FieldManager is the manager of the fields written.
+	FieldManager string
+}
+
+// Result is the outcome of the import of a MyResource.
+type Result struct {
+	Namespace string
+	Name      string
+	Action    Action
+	// Diff is the unified diff from the live MyResource to the imported one,
+	// when it is Updated, or the imported one in full when it is Created.
+	Diff string
+}
+
+// Import creates the MyResources myresources, or updates their spec, labels
+// and annotations when they exist. The imported labels and annotations are
+// merged into the live ones: those of the backup win, those only set in the
+// target cluster, e.g. by its controllers, are kept. It stops at the first
+// error, with the results of the MyResources imported until then.
+func Import(ctx context.Context, cs clientset.Interface, myresources []*v1alpha1.MyResource, opts ImportOptions) ([]Result, error) {
+	var dryRun []string
+	if opts.DryRun {
+		dryRun = []string{metav1.DryRunAll}
+	}
+	results := make([]Result, 0, len(myresources))
+	for _, myres := range myresources {
+		desired := Strip(myres)
+		if opts.Namespace != "" {
+			desired.Namespace = opts.Namespace
+		}
+		if desired.Namespace == "" {
+			desired.Namespace = metav1.NamespaceDefault
+		}
+		result := Result{Namespace: desired.Namespace, Name: desired.Name}
+		client := cs.MygroupV1alpha1().MyResources(desired.Namespace)
+
+		live, err := client.Get(ctx, desired.Name, metav1.GetOptions{})
+		switch {
+		case apierrors.IsNotFound(err):
+			if result.Diff, err = diff(nil, desired); err != nil {
+				return results, err
+			}
+			_, err = client.Create(ctx, desired, metav1.CreateOptions{DryRun: dryRun, FieldManager: opts.FieldManager})
+			if err != nil {
+				return results, err
+			}
+			result.Action = Created
+		case err != nil:
+			return results, err
+		default:
+			current := Strip(live)
+			desired.Labels = merge(current.Labels, desired.Labels)
+			desired.Annotations = merge(current.Annotations, desired.Annotations)
+			if equality.Semantic.DeepEqual(current.Spec, desired.Spec) &&
+				equality.Semantic.DeepEqual(current.Labels, desired.Labels) &&
+				equality.Semantic.DeepEqual(current.Annotations, desired.Annotations) {
+				result.Action = Unchanged
+				break
+			}
+			if result.Diff, err = diff(current, desired); err != nil {
+				return results, err
+			}
+			updated := live.DeepCopy()
+			updated.Labels = desired.Labels
+			updated.Annotations = desired.Annotations
+			if value, ok := live.Annotations[_lastAppliedAnnotation]; ok {
+				if updated.Annotations == nil {
+					updated.Annotations = map[string]string{}
+				}
+				updated.Annotations[_lastAppliedAnnotation] = value
+			}
+			updated.Spec = desired.Spec
+			_, err = client.Update(ctx, updated, metav1.UpdateOptions{DryRun: dryRun, FieldManager: opts.FieldManager})
+			if err != nil {
+				return results, err
+			}
+			result.Action = Updated
+		}
+		results = append(results, result)
+	}
+	return results, nil
+}
+
+// merge returns the entries of live overridden and completed by the ones of
+// imported, nil when there are none.
+func merge(live, imported map[string]string) map[string]string {
+	if len(live) == 0 {
+		return imported
+	}
+	merged := make(map[string]string, len(live)+len(imported))
+	for key, value := range live {
+		merged[key] = value
+	}
+	for key, value := range imported {
+		merged[key] = value
+	}
+	return merged
+}
+
+// diff returns the unified diff of the YAML of from and to, from being nil
+// when to is created.
+func diff(from, to *v1alpha1.MyResource) (string, error) {
+	var fromYAML []byte
+	if from != nil {
+		var err error
+		if fromYAML, err = Marshal(from); err != nil {
+			return "", err
+		}
+	}
+	toYAML, err := Marshal(to)
+	if err != nil {
+		return "", err
+	}
+	name := to.Namespace + "/" + to.Name
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:        difflib.SplitLines(string(fromYAML)),
+		B:        difflib.SplitLines(string(toYAML)),
+		FromFile: "live/" + name,
+		ToFile:   "imported/" + name,
+		Context:  3,
+	})
+}
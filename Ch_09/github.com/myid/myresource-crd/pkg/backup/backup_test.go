@@ -0,0 +1,244 @@
+package backup
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/enhancedfake"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func newMyResource(namespace, name, image string, labels map[string]string) *v1alpha1.MyResource {
+	return &v1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{Name: name, Namespace: namespace, Labels: labels},
+		Spec: v1alpha1.MyResourceSpec{
+			Image:  image,
+			Memory: resource.MustParse("1Gi"),
+		},
+	}
+}
+
+func newSourceClientset() *enhancedfake.Clientset {
+	myres1 := newMyResource("team-a", "myres1", "nginx", map[string]string{"app": "web"})
+	myres1.Annotations = map[string]string{_lastAppliedAnnotation: "{}"}
+	myres1.Status.State = "Ready"
+	return enhancedfake.NewClientset(
+		newMyResource("team-b", "myres3", "busybox", nil),
+		myres1,
+		newMyResource("team-a", "myres2", "busybox", nil),
+	)
+}
+
+func TestExport(t *testing.T) {
+	tests := []struct {
+		name string
+		opts ExportOptions
+		want []string
+	}{
+		{
+			name: "case 1: all the MyResources",
+			want: []string{"team-a/myres1", "team-a/myres2", "team-b/myres3"},
+		},
+		{
+			name: "case 2: in a namespace",
+			opts: ExportOptions{Namespace: "team-b"},
+			want: []string{"team-b/myres3"},
+		},
+		{
+			name: "case 3: with a label selector",
+			opts: ExportOptions{LabelSelector: "app=web"},
+			want: []string{"team-a/myres1"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			exported, err := Export(context.Background(), newSourceClientset(), tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			var got []string
+			for _, myres := range exported {
+				got = append(got, myres.Namespace+"/"+myres.Name)
+				if myres.ResourceVersion != "" || myres.UID != "" || myres.Generation != 0 ||
+					len(myres.ManagedFields) != 0 || myres.Status.State != "" {
+					t.Errorf("%s/%s is not stripped: %v", myres.Namespace, myres.Name, myres)
+				}
+				if _, ok := myres.Annotations[_lastAppliedAnnotation]; ok {
+					t.Errorf("%s/%s has the %s annotation", myres.Namespace, myres.Name, _lastAppliedAnnotation)
+				}
+			}
+			if strings.Join(got, ",") != strings.Join(tt.want, ",") {
+				t.Errorf("Export() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestSaveLoad(t *testing.T) {
+	exported, err := Export(context.Background(), newSourceClientset(), ExportOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	for _, name := range []string{"backup", "backup.tar.gz"} {
+		t.Run(name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), name)
+			if err := Save(path, exported); err != nil {
+				t.Fatal(err)
+			}
+			if !IsTarball(path) {
+				data, err := os.ReadFile(filepath.Join(path, "team-a", "myres1.yaml"))
+				if err != nil {
+					t.Fatal(err)
+				}
+				for _, field := range []string{"creationTimestamp", "resourceVersion", "managedFields", "status"} {
+					if strings.Contains(string(data), field) {
+						t.Errorf("the YAML of myres1 contains %s:\n%s", field, data)
+					}
+				}
+			}
+
+			loaded, err := Load(path)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(loaded) != len(exported) {
+				t.Fatalf("# of MyResources should be %d but is %d", len(exported), len(loaded))
+			}
+			for i := range loaded {
+				if loaded[i].Name != exported[i].Name || loaded[i].Namespace != exported[i].Namespace ||
+					loaded[i].Spec.Memory.Cmp(exported[i].Spec.Memory) != 0 {
+					t.Errorf("loaded %v, want %v", loaded[i], exported[i])
+				}
+			}
+		})
+	}
+}
+
+func TestUnmarshal(t *testing.T) {
+	tests := []struct {
+		name    string
+		data    string
+		wantErr bool
+	}{
+		{
+			name: "case 1: MyResource",
+			data: "apiVersion: mygroup.example.com/v1alpha1\nkind: MyResource\nmetadata:\n  name: myres1\nspec:\n  image: nginx\n  memory: 1Gi\n",
+		},
+		{
+			name:    "case 2: another kind",
+			data:    "apiVersion: v1\nkind: ConfigMap\nmetadata:\n  name: myres1\n",
+			wantErr: true,
+		},
+		{
+			name:    "case 3: unknown field",
+			data:    "apiVersion: mygroup.example.com/v1alpha1\nkind: MyResource\nmetadata:\n  name: myres1\nspec:\n  images: nginx\n",
+			wantErr: true,
+		},
+		{
+			name:    "case 4: no name",
+			data:    "apiVersion: mygroup.example.com/v1alpha1\nkind: MyResource\nspec:\n  image: nginx\n",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			_, err := Unmarshal([]byte(tt.data))
+			if (err != nil) != tt.wantErr {
+				t.Errorf("Unmarshal() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func TestImport(t *testing.T) {
+	imported := []*v1alpha1.MyResource{
+		newMyResource("team-a", "myres1", "nginx", map[string]string{"app": "web"}),
+		newMyResource("team-a", "myres2", "nginx:1.27", nil),
+		newMyResource("team-b", "myres3", "busybox", nil),
+	}
+	tests := []struct {
+		name        string
+		opts        ImportOptions
+		wantActions []Action
+		wantImage   string
+		wantCreated bool
+	}{
+		{
+			name:        "case 1: create or update",
+			wantActions: []Action{Unchanged, Updated, Created},
+			wantImage:   "nginx:1.27",
+			wantCreated: true,
+		},
+		{
+			name:        "case 2: dry run",
+			opts:        ImportOptions{DryRun: true},
+			wantActions: []Action{Unchanged, Updated, Created},
+			wantImage:   "busybox",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			// the labels set in the target cluster only are kept
+			cs := enhancedfake.NewClientset(
+				newMyResource("team-a", "myres1", "nginx", map[string]string{"app": "web", "zone": "east"}),
+				newMyResource("team-a", "myres2", "busybox", map[string]string{"zone": "east"}),
+			)
+			results, err := Import(ctx, cs, imported, tt.opts)
+			if err != nil {
+				t.Fatal(err)
+			}
+			if len(results) != len(tt.wantActions) {
+				t.Fatalf("# of results should be %d but is %d", len(tt.wantActions), len(results))
+			}
+			for i, result := range results {
+				if result.Action != tt.wantActions[i] {
+					t.Errorf("%s/%s action = %s, want %s", result.Namespace, result.Name, result.Action, tt.wantActions[i])
+				}
+			}
+			if diff := results[1].Diff; !strings.Contains(diff, "-  image: busybox") || !strings.Contains(diff, "+  image: nginx:1.27") {
+				t.Errorf("diff of myres2 =\n%s", diff)
+			}
+			if diff := results[2].Diff; !strings.Contains(diff, "+  image: busybox") || !strings.Contains(diff, "+  name: myres3") {
+				t.Errorf("diff of myres3 =\n%s", diff)
+			}
+
+			myres2, err := cs.MygroupV1alpha1().MyResources("team-a").Get(ctx, "myres2", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if myres2.Spec.Image != tt.wantImage {
+				t.Errorf("image of myres2 = %q, want %q", myres2.Spec.Image, tt.wantImage)
+			}
+			if myres2.Labels["zone"] != "east" {
+				t.Errorf("labels of myres2 = %v, want the live zone kept", myres2.Labels)
+			}
+			_, err = cs.MygroupV1alpha1().MyResources("team-b").Get(ctx, "myres3", metav1.GetOptions{})
+			if created := !apierrors.IsNotFound(err); created != tt.wantCreated {
+				t.Errorf("myres3 created = %v, want %v (%v)", created, tt.wantCreated, err)
+			}
+		})
+	}
+}
+
+func TestImport_namespace(t *testing.T) {
+	ctx := context.Background()
+	cs := enhancedfake.NewClientset()
+	results, err := Import(ctx, cs, []*v1alpha1.MyResource{newMyResource("team-a", "myres1", "nginx", nil)},
+		ImportOptions{Namespace: "team-c"})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if results[0].Namespace != "team-c" || results[0].Action != Created {
+		t.Errorf("Import() = %v", results[0])
+	}
+	if _, err := cs.MygroupV1alpha1().MyResources("team-c").Get(ctx, "myres1", metav1.GetOptions{}); err != nil {
+		t.Error(err)
+	}
+}
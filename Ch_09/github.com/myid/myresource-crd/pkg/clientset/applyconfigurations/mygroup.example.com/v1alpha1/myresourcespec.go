@@ -0,0 +1,51 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by applyconfiguration-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	resource "k8s.io/apimachinery/pkg/api/resource"
+)
+
+// MyResourceSpecApplyConfiguration represents an declarative configuration of the MyResourceSpec type for use
+// with apply.
+type MyResourceSpecApplyConfiguration struct {
+	Image  *string            `json:"image,omitempty"`
+	Memory *resource.Quantity `json:"memory,omitempty"`
+}
+
+// MyResourceSpecApplyConfiguration constructs an declarative configuration of the MyResourceSpec type for use with
+// apply.
+func MyResourceSpec() *MyResourceSpecApplyConfiguration {
+	return &MyResourceSpecApplyConfiguration{}
+}
+
+// WithImage sets the Image field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Image field is set to the value of the last call.
+func (b *MyResourceSpecApplyConfiguration) WithImage(value string) *MyResourceSpecApplyConfiguration {
+	b.Image = &value
+	return b
+}
+
+// WithMemory sets the Memory field in the declarative configuration to the given value
+// and returns the receiver, so that objects can be built by chaining "With" function invocations.
+// If called multiple times, the Memory field is set to the value of the last call.
+func (b *MyResourceSpecApplyConfiguration) WithMemory(value resource.Quantity) *MyResourceSpecApplyConfiguration {
+	b.Memory = &value
+	return b
+}
@@ -32,8 +32,7 @@ type Interface interface {
 	MygroupV1alpha1() mygroupv1alpha1.MygroupV1alpha1Interface
 }
 
-// Clientset contains the clients for groups. Each group has exactly one
-// version included in a Clientset.
+// Clientset contains the clients for groups.
 type Clientset struct {
 	*discovery.DiscoveryClient
 	mygroupV1alpha1 *mygroupv1alpha1.MygroupV1alpha1Client
@@ -19,8 +19,11 @@ package fake
 
 import (
 	"context"
+	json "encoding/json"
+	"fmt"
 
 	v1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	mygroupexamplecomv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/applyconfigurations/mygroup.example.com/v1alpha1"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	labels "k8s.io/apimachinery/pkg/labels"
 	schema "k8s.io/apimachinery/pkg/runtime/schema"
@@ -139,3 +142,48 @@ func (c *FakeMyResources) Patch(ctx context.Context, name string, pt types.Patch
 	}
 	return obj.(*v1alpha1.MyResource), err
 }
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied myResource.
+func (c *FakeMyResources) Apply(ctx context.Context, myResource *mygroupexamplecomv1alpha1.MyResourceApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.MyResource, err error) {
+	if myResource == nil {
+		return nil, fmt.Errorf("myResource provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(myResource)
+	if err != nil {
+		return nil, err
+	}
+	name := myResource.Name
+	if name == nil {
+		return nil, fmt.Errorf("myResource.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(myresourcesResource, c.ns, *name, types.ApplyPatchType, data), &v1alpha1.MyResource{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MyResource), err
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *FakeMyResources) ApplyStatus(ctx context.Context, myResource *mygroupexamplecomv1alpha1.MyResourceApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.MyResource, err error) {
+	if myResource == nil {
+		return nil, fmt.Errorf("myResource provided to Apply must not be nil")
+	}
+	data, err := json.Marshal(myResource)
+	if err != nil {
+		return nil, err
+	}
+	name := myResource.Name
+	if name == nil {
+		return nil, fmt.Errorf("myResource.Name must be provided to Apply")
+	}
+	obj, err := c.Fake.
+		Invokes(testing.NewPatchSubresourceAction(myresourcesResource, c.ns, *name, types.ApplyPatchType, data, "status"), &v1alpha1.MyResource{})
+
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MyResource), err
+}
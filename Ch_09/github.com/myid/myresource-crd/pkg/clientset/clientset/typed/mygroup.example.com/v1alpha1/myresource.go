@@ -19,9 +19,12 @@ package v1alpha1
 
 import (
 	"context"
+	json "encoding/json"
+	"fmt"
 	"time"
 
 	v1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	mygroupexamplecomv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/applyconfigurations/mygroup.example.com/v1alpha1"
 	scheme "github.com/myid/myresource-crd/pkg/clientset/clientset/scheme"
 	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	types "k8s.io/apimachinery/pkg/types"
@@ -46,6 +49,8 @@ type MyResourceInterface interface {
 	List(ctx context.Context, opts v1.ListOptions) (*v1alpha1.MyResourceList, error)
 	Watch(ctx context.Context, opts v1.ListOptions) (watch.Interface, error)
 	Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts v1.PatchOptions, subresources ...string) (result *v1alpha1.MyResource, err error)
+	Apply(ctx context.Context, myResource *mygroupexamplecomv1alpha1.MyResourceApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.MyResource, err error)
+	ApplyStatus(ctx context.Context, myResource *mygroupexamplecomv1alpha1.MyResourceApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.MyResource, err error)
 	MyResourceExpansion
 }
 
@@ -192,3 +197,59 @@ func (c *myResources) Patch(ctx context.Context, name string, pt types.PatchType
 		Into(result)
 	return
 }
+
+// Apply takes the given apply declarative configuration, applies it and returns the applied myResource.
+func (c *myResources) Apply(ctx context.Context, myResource *mygroupexamplecomv1alpha1.MyResourceApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.MyResource, err error) {
+	if myResource == nil {
+		return nil, fmt.Errorf("myResource provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(myResource)
+	if err != nil {
+		return nil, err
+	}
+	name := myResource.Name
+	if name == nil {
+		return nil, fmt.Errorf("myResource.Name must be provided to Apply")
+	}
+	result = &v1alpha1.MyResource{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("myresources").
+		Name(*name).
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
+
+// ApplyStatus was generated because the type contains a Status member.
+// Add a +genclient:noStatus comment above the type to avoid generating ApplyStatus().
+func (c *myResources) ApplyStatus(ctx context.Context, myResource *mygroupexamplecomv1alpha1.MyResourceApplyConfiguration, opts v1.ApplyOptions) (result *v1alpha1.MyResource, err error) {
+	if myResource == nil {
+		return nil, fmt.Errorf("myResource provided to Apply must not be nil")
+	}
+	patchOpts := opts.ToPatchOptions()
+	data, err := json.Marshal(myResource)
+	if err != nil {
+		return nil, err
+	}
+
+	name := myResource.Name
+	if name == nil {
+		return nil, fmt.Errorf("myResource.Name must be provided to Apply")
+	}
+
+	result = &v1alpha1.MyResource{}
+	err = c.client.Patch(types.ApplyPatchType).
+		Namespace(c.ns).
+		Resource("myresources").
+		Name(*name).
+		SubResource("status").
+		VersionedParams(&patchOpts, scheme.ParameterCodec).
+		Body(data).
+		Do(ctx).
+		Into(result)
+	return
+}
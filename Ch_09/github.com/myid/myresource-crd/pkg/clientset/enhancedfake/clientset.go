@@ -0,0 +1,64 @@
+// Package enhancedfake provides a fake clientset for MyResource that behaves
+// like the API server where the generated fake does not: the status subresource,
+// the generation, resourceVersion conflicts and server-side apply.
+package enhancedfake
+
+import (
+	"github.com/myid/myresource-crd/pkg/clientset/clientset"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset/fake"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset/scheme"
+	typedv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/clientset/typed/mygroup.example.com/v1alpha1"
+	fakev1alpha1 "github.com/myid/myresource-crd/pkg/clientset/clientset/typed/mygroup.example.com/v1alpha1/fake"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/testing"
+)
+
+// Clientset is a fake clientset whose MyResources are handled like the API
+// server does, the other resources like the generated fake.
+type Clientset struct {
+	*fake.Clientset
+	tracker testing.ObjectTracker
+}
+
+var (
+	_ clientset.Interface = &Clientset{}
+	_ testing.FakeClient  = &Clientset{}
+)
+
+// NewClientset returns a clientset holding objects. The MyResources get a
+// generation and a resourceVersion when they have none.
+func NewClientset(objects ...runtime.Object) *Clientset {
+	tracker := testing.NewObjectTracker(scheme.Scheme, scheme.Codecs.UniversalDecoder())
+	server, err := newServer(tracker)
+	if err != nil {
+		panic(err)
+	}
+	for _, obj := range objects {
+		if err := server.add(obj); err != nil {
+			panic(err)
+		}
+	}
+
+	cs := &Clientset{Clientset: fake.NewSimpleClientset(), tracker: tracker}
+	cs.PrependReactor("*", "*", testing.ObjectReaction(tracker))
+	cs.PrependReactor("*", myresourcesResource.Resource, server.react)
+	cs.PrependWatchReactor("*", func(action testing.Action) (bool, watch.Interface, error) {
+		w, err := tracker.Watch(action.GetResource(), action.GetNamespace())
+		if err != nil {
+			return false, nil, err
+		}
+		return true, w, nil
+	})
+	return cs
+}
+
+// Tracker returns the tracker holding the objects of the clientset.
+func (c *Clientset) Tracker() testing.ObjectTracker {
+	return c.tracker
+}
+
+// MygroupV1alpha1 retrieves the MygroupV1alpha1Client
+func (c *Clientset) MygroupV1alpha1() typedv1alpha1.MygroupV1alpha1Interface {
+	return &mygroupV1alpha1{FakeMygroupV1alpha1: &fakev1alpha1.FakeMygroupV1alpha1{Fake: &c.Fake}}
+}
@@ -0,0 +1,82 @@
+package enhancedfake
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	applyv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/applyconfigurations/mygroup.example.com/v1alpha1"
+	typedv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/clientset/typed/mygroup.example.com/v1alpha1"
+	fakev1alpha1 "github.com/myid/myresource-crd/pkg/clientset/clientset/typed/mygroup.example.com/v1alpha1/fake"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/testing"
+)
+
+type mygroupV1alpha1 struct {
+	*fakev1alpha1.FakeMygroupV1alpha1
+}
+
+func (c *mygroupV1alpha1) MyResources(namespace string) typedv1alpha1.MyResourceInterface {
+	return &myResources{
+		MyResourceInterface: c.FakeMygroupV1alpha1.MyResources(namespace),
+		fake:                c.Fake,
+		ns:                  namespace,
+	}
+}
+
+// myResources passes the options of the writes on to the reactors, which the
+// generated fake drops. The reads and deletes are left to the generated fake.
+type myResources struct {
+	typedv1alpha1.MyResourceInterface
+	fake *testing.Fake
+	ns   string
+}
+
+func (c *myResources) Create(ctx context.Context, myResource *v1alpha1.MyResource, opts metav1.CreateOptions) (*v1alpha1.MyResource, error) {
+	return c.invoke(testing.NewCreateActionWithOptions(myresourcesResource, c.ns, myResource, opts))
+}
+
+func (c *myResources) Update(ctx context.Context, myResource *v1alpha1.MyResource, opts metav1.UpdateOptions) (*v1alpha1.MyResource, error) {
+	return c.invoke(testing.NewUpdateActionWithOptions(myresourcesResource, c.ns, myResource, opts))
+}
+
+func (c *myResources) UpdateStatus(ctx context.Context, myResource *v1alpha1.MyResource, opts metav1.UpdateOptions) (*v1alpha1.MyResource, error) {
+	return c.invoke(testing.NewUpdateSubresourceActionWithOptions(myresourcesResource, "status", c.ns, myResource, opts))
+}
+
+func (c *myResources) Patch(ctx context.Context, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*v1alpha1.MyResource, error) {
+	return c.invoke(testing.NewPatchSubresourceActionWithOptions(myresourcesResource, c.ns, name, pt, data, opts, subresources...))
+}
+
+func (c *myResources) Apply(ctx context.Context, myResource *applyv1alpha1.MyResourceApplyConfiguration, opts metav1.ApplyOptions) (*v1alpha1.MyResource, error) {
+	return c.apply(myResource, opts)
+}
+
+func (c *myResources) ApplyStatus(ctx context.Context, myResource *applyv1alpha1.MyResourceApplyConfiguration, opts metav1.ApplyOptions) (*v1alpha1.MyResource, error) {
+	return c.apply(myResource, opts, "status")
+}
+
+func (c *myResources) apply(myResource *applyv1alpha1.MyResourceApplyConfiguration, opts metav1.ApplyOptions, subresources ...string) (*v1alpha1.MyResource, error) {
+	if myResource == nil {
+		return nil, fmt.Errorf("myResource provided to Apply must not be nil")
+	}
+	if myResource.Name == nil {
+		return nil, fmt.Errorf("myResource.Name must be provided to Apply")
+	}
+	data, err := json.Marshal(myResource)
+	if err != nil {
+		return nil, err
+	}
+	return c.invoke(testing.NewPatchSubresourceActionWithOptions(myresourcesResource, c.ns, *myResource.Name,
+		types.ApplyPatchType, data, opts.ToPatchOptions(), subresources...))
+}
+
+func (c *myResources) invoke(action testing.Action) (*v1alpha1.MyResource, error) {
+	obj, err := c.fake.Invokes(action, &v1alpha1.MyResource{})
+	if obj == nil {
+		return nil, err
+	}
+	return obj.(*v1alpha1.MyResource), err
+}
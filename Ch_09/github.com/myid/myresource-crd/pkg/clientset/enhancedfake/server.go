@@ -0,0 +1,399 @@
+package enhancedfake
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"sync"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset/scheme"
+	jsonpatch "gopkg.in/evanphx/json-patch.v4"
+	apiequality "k8s.io/apimachinery/pkg/api/equality"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/util/managedfields"
+	utilrand "k8s.io/apimachinery/pkg/util/rand"
+	"k8s.io/apimachinery/pkg/util/uuid"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+	"k8s.io/client-go/testing"
+	"sigs.k8s.io/structured-merge-diff/v4/fieldpath"
+	"sigs.k8s.io/yaml"
+)
+
+// defaultFieldManager owns the fields of the writes that don't name a field
+// manager, the API server derives it from the user agent instead.
+const defaultFieldManager = "enhancedfake"
+
+var (
+	myresourcesResource = v1alpha1.SchemeGroupVersion.WithResource("myresources")
+	myresourcesKind     = v1alpha1.SchemeGroupVersion.WithKind("MyResource")
+)
+
+// server handles the writes of MyResources the way the API server does for a
+// custom resource with the status subresource enabled.
+type server struct {
+	lock    sync.Mutex
+	tracker testing.ObjectTracker
+	// managers are the field managers by subresource
+	managers        map[string]*managedfields.FieldManager
+	resourceVersion int64
+}
+
+func newServer(tracker testing.ObjectTracker) (*server, error) {
+	s := &server{
+		tracker:  tracker,
+		managers: map[string]*managedfields.FieldManager{},
+	}
+	// like the API server, the main resource ignores the status and the status
+	// subresource ignores the spec
+	for subresource, reset := range map[string]string{"": "status", "status": "spec"} {
+		mgr, err := managedfields.NewDefaultFieldManager(
+			managedfields.NewDeducedTypeConverter(),
+			scheme.Scheme,
+			scheme.Scheme,
+			scheme.Scheme,
+			myresourcesKind,
+			myresourcesKind.GroupVersion(),
+			subresource,
+			map[fieldpath.APIVersion]*fieldpath.Set{
+				fieldpath.APIVersion(v1alpha1.SchemeGroupVersion.String()): fieldpath.NewSet(fieldpath.MakePathOrDie(reset)),
+			},
+		)
+		if err != nil {
+			return nil, err
+		}
+		s.managers[subresource] = mgr
+	}
+	return s, nil
+}
+
+// add adds obj to the tracker, as is unless it is a MyResource without a
+// generation or a resourceVersion.
+func (s *server) add(obj runtime.Object) error {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	myres, ok := obj.(*v1alpha1.MyResource)
+	if !ok {
+		return s.tracker.Add(obj)
+	}
+	myres = myres.DeepCopy()
+	if myres.Generation == 0 {
+		myres.Generation = 1
+	}
+	if myres.UID == "" {
+		myres.UID = uuid.NewUUID()
+	}
+	if myres.ResourceVersion == "" {
+		s.resourceVersion++
+		myres.ResourceVersion = strconv.FormatInt(s.resourceVersion, 10)
+	} else if rv, err := strconv.ParseInt(myres.ResourceVersion, 10, 64); err == nil && rv > s.resourceVersion {
+		s.resourceVersion = rv
+	}
+	return s.tracker.Add(myres)
+}
+
+// react handles the creates, updates and patches of MyResources and leaves the
+// other actions to the next reactors.
+func (s *server) react(action testing.Action) (bool, runtime.Object, error) {
+	s.lock.Lock()
+	defer s.lock.Unlock()
+
+	var obj runtime.Object
+	var err error
+	switch action := action.(type) {
+	case testing.CreateActionImpl:
+		if action.GetSubresource() != "" {
+			return false, nil, nil
+		}
+		obj, err = s.create(action)
+	case testing.UpdateActionImpl:
+		obj, err = s.update(action)
+	case testing.PatchActionImpl:
+		if action.GetPatchType() == types.ApplyPatchType {
+			obj, err = s.apply(action)
+		} else {
+			obj, err = s.patch(action)
+		}
+	default:
+		return false, nil, nil
+	}
+	if err != nil {
+		return true, nil, err
+	}
+	return true, obj, nil
+}
+
+func (s *server) create(action testing.CreateActionImpl) (*v1alpha1.MyResource, error) {
+	myres, ok := action.GetObject().(*v1alpha1.MyResource)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("not a MyResource: %T", action.GetObject()))
+	}
+	if myres.ResourceVersion != "" {
+		return nil, apierrors.NewBadRequest("resourceVersion should not be set on objects to be created")
+	}
+	myres = myres.DeepCopy()
+	if myres.Name == "" {
+		if myres.GenerateName == "" {
+			return nil, invalid(myres.Name, field.Required(field.NewPath("metadata", "name"), "name or generateName is required"))
+		}
+		myres.Name = myres.GenerateName + utilrand.String(5)
+	}
+	if myres.Namespace == "" {
+		myres.Namespace = action.GetNamespace()
+	}
+	myres.ManagedFields = nil
+
+	obj, err := s.managers[""].Update(newMyResource(), myres, fieldManagerOr(action.CreateOptions.FieldManager))
+	if err != nil {
+		return nil, err
+	}
+	created, err := toMyResource(obj)
+	if err != nil {
+		return nil, err
+	}
+	return s.store(prepareCreate(created), true, action.CreateOptions.DryRun)
+}
+
+func (s *server) update(action testing.UpdateActionImpl) (*v1alpha1.MyResource, error) {
+	myres, ok := action.GetObject().(*v1alpha1.MyResource)
+	if !ok {
+		return nil, apierrors.NewBadRequest(fmt.Sprintf("not a MyResource: %T", action.GetObject()))
+	}
+	live, err := s.get(action.GetNamespace(), myres.Name)
+	if err != nil {
+		return nil, err
+	}
+	// unconditional updates are not allowed for custom resources
+	if myres.ResourceVersion == "" {
+		return nil, invalid(myres.Name, field.Invalid(field.NewPath("metadata", "resourceVersion"),
+			myres.ResourceVersion, "must be specified for an update"))
+	}
+	if myres.ResourceVersion != live.ResourceVersion {
+		return nil, conflict(myres.Name)
+	}
+	return s.save(live, myres, action.GetSubresource(), action.UpdateOptions.FieldManager, action.UpdateOptions.DryRun)
+}
+
+func (s *server) patch(action testing.PatchActionImpl) (*v1alpha1.MyResource, error) {
+	live, err := s.get(action.GetNamespace(), action.GetName())
+	if err != nil {
+		return nil, err
+	}
+	original, err := json.Marshal(live)
+	if err != nil {
+		return nil, err
+	}
+
+	var modified []byte
+	switch action.GetPatchType() {
+	case types.JSONPatchType:
+		patch, err := jsonpatch.DecodePatch(action.GetPatch())
+		if err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+		if modified, err = patch.Apply(original); err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	case types.MergePatchType:
+		if modified, err = jsonpatch.MergePatch(original, action.GetPatch()); err != nil {
+			return nil, apierrors.NewBadRequest(err.Error())
+		}
+	default:
+		// custom resources have no patch strategy
+		return nil, apierrors.NewGenericServerResponse(http.StatusUnsupportedMediaType, "patch",
+			myresourcesResource.GroupResource(), action.GetName(),
+			fmt.Sprintf("the body of the request was in an unknown format - accepted media types include: %s, %s, %s",
+				types.JSONPatchType, types.MergePatchType, types.ApplyPatchType), 0, false)
+	}
+
+	patched := &v1alpha1.MyResource{}
+	if err := json.Unmarshal(modified, patched); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	// a resourceVersion in the patch is a precondition
+	if patched.ResourceVersion != live.ResourceVersion {
+		return nil, conflict(action.GetName())
+	}
+	return s.save(live, patched, action.GetSubresource(), action.PatchOptions.FieldManager, action.PatchOptions.DryRun)
+}
+
+func (s *server) apply(action testing.PatchActionImpl) (*v1alpha1.MyResource, error) {
+	opts := action.PatchOptions
+	if opts.FieldManager == "" {
+		return nil, apierrors.NewBadRequest("PatchOptions.fieldManager is required for apply requests")
+	}
+	applied := &unstructured.Unstructured{}
+	if err := yaml.Unmarshal(action.GetPatch(), &applied.Object); err != nil {
+		return nil, apierrors.NewBadRequest(err.Error())
+	}
+	if applied.GetName() != action.GetName() {
+		return nil, apierrors.NewBadRequest("the name of the object does not match the name on the URL")
+	}
+
+	subresource := action.GetSubresource()
+	live, err := s.get(action.GetNamespace(), action.GetName())
+	exists := err == nil
+	if apierrors.IsNotFound(err) && subresource == "" {
+		live = newMyResource()
+		live.Namespace = action.GetNamespace()
+	} else if err != nil {
+		return nil, err
+	}
+
+	mgr, err := s.manager(subresource)
+	if err != nil {
+		return nil, err
+	}
+	force := opts.Force != nil && *opts.Force
+	obj, err := mgr.Apply(live, applied, opts.FieldManager, force)
+	if err != nil {
+		return nil, err
+	}
+	result, err := toMyResource(obj)
+	if err != nil {
+		return nil, err
+	}
+	if !exists {
+		return s.store(prepareCreate(result), true, opts.DryRun)
+	}
+	updated := prepareUpdate(live, result, subresource)
+	if apiequality.Semantic.DeepEqual(live, updated) {
+		return live, nil
+	}
+	return s.store(updated, false, opts.DryRun)
+}
+
+// save records the fields of the manager and stores the update of live to
+// myres, unless it changes nothing.
+func (s *server) save(live, myres *v1alpha1.MyResource, subresource, manager string, dryRun []string) (*v1alpha1.MyResource, error) {
+	mgr, err := s.manager(subresource)
+	if err != nil {
+		return nil, err
+	}
+	updated := prepareUpdate(live, myres, subresource)
+	obj, err := mgr.Update(live, updated, fieldManagerOr(manager))
+	if err != nil {
+		return nil, err
+	}
+	if updated, err = toMyResource(obj); err != nil {
+		return nil, err
+	}
+	if apiequality.Semantic.DeepEqual(live, updated) {
+		return live, nil
+	}
+	return s.store(updated, false, dryRun)
+}
+
+// store gives myres the next resourceVersion and stores it, unless dryRun is set.
+func (s *server) store(myres *v1alpha1.MyResource, create bool, dryRun []string) (*v1alpha1.MyResource, error) {
+	if len(dryRun) > 0 {
+		return myres, nil
+	}
+	s.resourceVersion++
+	myres.ResourceVersion = strconv.FormatInt(s.resourceVersion, 10)
+
+	var err error
+	if create {
+		err = s.tracker.Create(myresourcesResource, myres, myres.Namespace)
+	} else {
+		err = s.tracker.Update(myresourcesResource, myres, myres.Namespace)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return myres.DeepCopy(), nil
+}
+
+func (s *server) get(namespace, name string) (*v1alpha1.MyResource, error) {
+	obj, err := s.tracker.Get(myresourcesResource, namespace, name)
+	if err != nil {
+		return nil, err
+	}
+	return toMyResource(obj)
+}
+
+func (s *server) manager(subresource string) (*managedfields.FieldManager, error) {
+	mgr, ok := s.managers[subresource]
+	if !ok {
+		return nil, apierrors.NewNotFound(myresourcesResource.GroupResource(), subresource)
+	}
+	return mgr, nil
+}
+
+// prepareCreate sets the fields of a new MyResource owned by the API server.
+func prepareCreate(myres *v1alpha1.MyResource) *v1alpha1.MyResource {
+	myres.SetGroupVersionKind(myresourcesKind)
+	myres.Status = v1alpha1.MyResourceStatus{}
+	myres.Generation = 1
+	myres.UID = uuid.NewUUID()
+	myres.CreationTimestamp = metav1.Now()
+	return myres
+}
+
+// prepareUpdate returns live updated with myres: the main resource ignores the
+// status and bumps the generation when the spec changes, the status
+// subresource ignores everything but the status.
+func prepareUpdate(live, myres *v1alpha1.MyResource, subresource string) *v1alpha1.MyResource {
+	var updated *v1alpha1.MyResource
+	if subresource == "status" {
+		updated = live.DeepCopy()
+		updated.Status = myres.Status
+	} else {
+		updated = myres.DeepCopy()
+		updated.TypeMeta = live.TypeMeta
+		updated.Status = live.Status
+		updated.UID = live.UID
+		updated.CreationTimestamp = live.CreationTimestamp
+		updated.DeletionTimestamp = live.DeletionTimestamp
+		updated.Generation = live.Generation
+		if !apiequality.Semantic.DeepEqual(live.Spec, updated.Spec) {
+			updated.Generation++
+		}
+	}
+	updated.ManagedFields = myres.ManagedFields
+	updated.ResourceVersion = live.ResourceVersion
+	return updated
+}
+
+func newMyResource() *v1alpha1.MyResource {
+	myres := &v1alpha1.MyResource{}
+	myres.SetGroupVersionKind(myresourcesKind)
+	return myres
+}
+
+func toMyResource(obj runtime.Object) (*v1alpha1.MyResource, error) {
+	switch obj := obj.(type) {
+	case *v1alpha1.MyResource:
+		return obj, nil
+	case *unstructured.Unstructured:
+		myres := &v1alpha1.MyResource{}
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(obj.Object, myres); err != nil {
+			return nil, err
+		}
+		return myres, nil
+	}
+	return nil, fmt.Errorf("not a MyResource: %T", obj)
+}
+
+func fieldManagerOr(manager string) string {
+	if manager == "" {
+		return defaultFieldManager
+	}
+	return manager
+}
+
+func conflict(name string) error {
+	return apierrors.NewConflict(myresourcesResource.GroupResource(), name,
+		errors.New("the object has been modified; please apply your changes to the latest version and try again"))
+}
+
+func invalid(name string, errs ...*field.Error) error {
+	return apierrors.NewInvalid(myresourcesKind.GroupKind(), name, errs)
+}
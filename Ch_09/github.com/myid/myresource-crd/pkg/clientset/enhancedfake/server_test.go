@@ -0,0 +1,293 @@
+package enhancedfake
+
+import (
+	"context"
+	"testing"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	applyv1alpha1 "github.com/myid/myresource-crd/pkg/clientset/applyconfigurations/mygroup.example.com/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+)
+
+func newMyResourceWithSpec(image string) *v1alpha1.MyResource {
+	return &v1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{Name: "myres", Namespace: "default"},
+		Spec: v1alpha1.MyResourceSpec{
+			Image:  image,
+			Memory: resource.MustParse("1Gi"),
+		},
+		Status: v1alpha1.MyResourceStatus{State: "Ready"},
+	}
+}
+
+func TestClientset(t *testing.T) {
+	type result struct {
+		generation int64
+		image      string
+		state      string
+		managers   []string
+	}
+	tests := []struct {
+		name    string
+		do      func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error)
+		want    result
+		wantErr func(error) bool
+	}{
+		{
+			name: "case 1: create drops the status",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				return c.MygroupV1alpha1().MyResources("default").Create(ctx, newMyResourceWithSpec("nginx"),
+					metav1.CreateOptions{FieldManager: "creator"})
+			},
+			want: result{generation: 1, image: "nginx", managers: []string{"creator"}},
+		},
+		{
+			name: "case 2: update of the spec bumps the generation and ignores the status",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				myres, err := create(ctx, c)
+				if err != nil {
+					return nil, err
+				}
+				myres.Spec.Image = "nginx:1.27"
+				myres.Status.State = "Ready"
+				return c.MygroupV1alpha1().MyResources("default").Update(ctx, myres,
+					metav1.UpdateOptions{FieldManager: "updater"})
+			},
+			want: result{generation: 2, image: "nginx:1.27", managers: []string{"creator", "updater"}},
+		},
+		{
+			name: "case 3: update of the status ignores the spec",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				myres, err := create(ctx, c)
+				if err != nil {
+					return nil, err
+				}
+				myres.Spec.Image = "nginx:1.27"
+				myres.Status.State = "Ready"
+				return c.MygroupV1alpha1().MyResources("default").UpdateStatus(ctx, myres,
+					metav1.UpdateOptions{FieldManager: "controller"})
+			},
+			want: result{generation: 1, image: "nginx", state: "Ready", managers: []string{"controller", "creator"}},
+		},
+		{
+			name: "case 4: update with a stale resourceVersion",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				myres, err := create(ctx, c)
+				if err != nil {
+					return nil, err
+				}
+				stale := myres.DeepCopy()
+				myres.Spec.Image = "nginx:1.27"
+				if _, err := c.MygroupV1alpha1().MyResources("default").Update(ctx, myres, metav1.UpdateOptions{}); err != nil {
+					return nil, err
+				}
+				stale.Spec.Image = "nginx:1.26"
+				return c.MygroupV1alpha1().MyResources("default").Update(ctx, stale, metav1.UpdateOptions{})
+			},
+			wantErr: apierrors.IsConflict,
+		},
+		{
+			name: "case 5: update without a resourceVersion",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				if _, err := create(ctx, c); err != nil {
+					return nil, err
+				}
+				return c.MygroupV1alpha1().MyResources("default").Update(ctx, newMyResourceWithSpec("nginx:1.27"),
+					metav1.UpdateOptions{})
+			},
+			wantErr: apierrors.IsInvalid,
+		},
+		{
+			name: "case 6: apply creates the object",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				return c.MygroupV1alpha1().MyResources("default").Apply(ctx, applyMyResource("nginx"),
+					metav1.ApplyOptions{FieldManager: "applier"})
+			},
+			want: result{generation: 1, image: "nginx", managers: []string{"applier"}},
+		},
+		{
+			name: "case 7: apply of a field owned by another manager",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				_, err := c.MygroupV1alpha1().MyResources("default").Apply(ctx, applyMyResource("nginx"),
+					metav1.ApplyOptions{FieldManager: "applier"})
+				if err != nil {
+					return nil, err
+				}
+				return c.MygroupV1alpha1().MyResources("default").Apply(ctx, applyMyResource("nginx:1.27"),
+					metav1.ApplyOptions{FieldManager: "other"})
+			},
+			wantErr: apierrors.IsConflict,
+		},
+		{
+			name: "case 8: forced apply takes the ownership",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				_, err := c.MygroupV1alpha1().MyResources("default").Apply(ctx, applyMyResource("nginx"),
+					metav1.ApplyOptions{FieldManager: "applier"})
+				if err != nil {
+					return nil, err
+				}
+				return c.MygroupV1alpha1().MyResources("default").Apply(ctx, applyMyResource("nginx:1.27"),
+					metav1.ApplyOptions{FieldManager: "other", Force: true})
+			},
+			want: result{generation: 2, image: "nginx:1.27", managers: []string{"applier", "other"}},
+		},
+		{
+			name: "case 9: apply of the status",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				if _, err := create(ctx, c); err != nil {
+					return nil, err
+				}
+				return c.MygroupV1alpha1().MyResources("default").ApplyStatus(ctx,
+					applyv1alpha1.MyResource("myres", "default").
+						WithSpec(applyv1alpha1.MyResourceSpec().WithImage("ignored")).
+						WithStatus(applyv1alpha1.MyResourceStatus().WithState("Ready")),
+					metav1.ApplyOptions{FieldManager: "controller"})
+			},
+			want: result{generation: 1, image: "nginx", state: "Ready", managers: []string{"controller", "creator"}},
+		},
+		{
+			name: "case 10: strategic merge patch",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				if _, err := create(ctx, c); err != nil {
+					return nil, err
+				}
+				return c.MygroupV1alpha1().MyResources("default").Patch(ctx, "myres", types.StrategicMergePatchType,
+					[]byte(`{"spec":{"image":"nginx:1.27"}}`), metav1.PatchOptions{})
+			},
+			wantErr: apierrors.IsUnsupportedMediaType,
+		},
+		{
+			name: "case 11: merge patch with a stale resourceVersion",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				if _, err := create(ctx, c); err != nil {
+					return nil, err
+				}
+				return c.MygroupV1alpha1().MyResources("default").Patch(ctx, "myres", types.MergePatchType,
+					[]byte(`{"metadata":{"resourceVersion":"0"},"spec":{"image":"nginx:1.27"}}`), metav1.PatchOptions{})
+			},
+			wantErr: apierrors.IsConflict,
+		},
+		{
+			name: "case 12: merge patch",
+			do: func(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+				if _, err := create(ctx, c); err != nil {
+					return nil, err
+				}
+				return c.MygroupV1alpha1().MyResources("default").Patch(ctx, "myres", types.MergePatchType,
+					[]byte(`{"spec":{"image":"nginx:1.27"}}`), metav1.PatchOptions{FieldManager: "patcher"})
+			},
+			want: result{generation: 2, image: "nginx:1.27", managers: []string{"creator", "patcher"}},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			ctx := context.Background()
+			c := NewClientset()
+			got, err := tt.do(ctx, c)
+			if tt.wantErr != nil {
+				if !tt.wantErr(err) {
+					t.Fatalf("error = %v, want another error", err)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatalf("error = %v", err)
+			}
+
+			stored, err := c.MygroupV1alpha1().MyResources("default").Get(ctx, "myres", metav1.GetOptions{})
+			if err != nil {
+				t.Fatal(err)
+			}
+			if got.ResourceVersion != stored.ResourceVersion {
+				t.Errorf("resourceVersion = %s, stored %s", got.ResourceVersion, stored.ResourceVersion)
+			}
+			if stored.Generation != tt.want.generation {
+				t.Errorf("generation = %d, want %d", stored.Generation, tt.want.generation)
+			}
+			if stored.Spec.Image != tt.want.image {
+				t.Errorf("image = %q, want %q", stored.Spec.Image, tt.want.image)
+			}
+			if stored.Status.State != tt.want.state {
+				t.Errorf("state = %q, want %q", stored.Status.State, tt.want.state)
+			}
+			var managers []string
+			for _, entry := range stored.ManagedFields {
+				managers = append(managers, entry.Manager)
+			}
+			if !equalSets(managers, tt.want.managers) {
+				t.Errorf("managers = %v, want %v", managers, tt.want.managers)
+			}
+		})
+	}
+}
+
+func TestClientset_noopUpdate(t *testing.T) {
+	ctx := context.Background()
+	c := NewClientset(newMyResourceWithSpec("nginx"))
+	myres, err := c.MygroupV1alpha1().MyResources("default").Get(ctx, "myres", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if myres.Generation != 1 || myres.ResourceVersion == "" {
+		t.Fatalf("added object has generation %d and resourceVersion %q", myres.Generation, myres.ResourceVersion)
+	}
+
+	updated, err := c.MygroupV1alpha1().MyResources("default").Update(ctx, myres, metav1.UpdateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if updated.ResourceVersion != myres.ResourceVersion {
+		t.Errorf("no-op update changed the resourceVersion from %s to %s", myres.ResourceVersion, updated.ResourceVersion)
+	}
+
+	myres.Spec.Image = "nginx:1.27"
+	dryRun, err := c.MygroupV1alpha1().MyResources("default").Update(ctx, myres,
+		metav1.UpdateOptions{DryRun: []string{metav1.DryRunAll}})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if dryRun.Generation != 2 {
+		t.Errorf("dry run generation = %d, want %d", dryRun.Generation, 2)
+	}
+	stored, err := c.MygroupV1alpha1().MyResources("default").Get(ctx, "myres", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if stored.Spec.Image != "nginx" {
+		t.Errorf("dry run stored image %q", stored.Spec.Image)
+	}
+}
+
+func create(ctx context.Context, c *Clientset) (*v1alpha1.MyResource, error) {
+	return c.MygroupV1alpha1().MyResources("default").Create(ctx, newMyResourceWithSpec("nginx"),
+		metav1.CreateOptions{FieldManager: "creator"})
+}
+
+func applyMyResource(image string) *applyv1alpha1.MyResourceApplyConfiguration {
+	return applyv1alpha1.MyResource("myres", "default").
+		WithSpec(applyv1alpha1.MyResourceSpec().
+			WithImage(image).
+			WithMemory(resource.MustParse("1Gi")))
+}
+
+func equalSets(a, b []string) bool {
+	if len(a) != len(b) {
+		return false
+	}
+	seen := map[string]int{}
+	for _, s := range a {
+		seen[s]++
+	}
+	for _, s := range b {
+		seen[s]--
+	}
+	for _, n := range seen {
+		if n != 0 {
+			return false
+		}
+	}
+	return true
+}
@@ -0,0 +1,89 @@
+/*
+Copyright The Kubernetes Authors.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+// Code generated by informer-gen. DO NOT EDIT.
+
+package v1alpha1
+
+import (
+	"context"
+	time "time"
+
+	mygroupexamplecomv1alpha1 "github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	clientset "github.com/myid/myresource-crd/pkg/clientset/clientset"
+	internalinterfaces "github.com/myid/myresource-crd/pkg/clientset/informers/externalversions/internalinterfaces"
+	v1alpha1 "github.com/myid/myresource-crd/pkg/clientset/listers/mygroup.example.com/v1alpha1"
+	v1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	runtime "k8s.io/apimachinery/pkg/runtime"
+	watch "k8s.io/apimachinery/pkg/watch"
+	cache "k8s.io/client-go/tools/cache"
+)
+
+// MyResourceInformer provides access to a shared informer and lister for
+// MyResources.
+type MyResourceInformer interface {
+	Informer() cache.SharedIndexInformer
+	Lister() v1alpha1.MyResourceLister
+}
+
+type myResourceInformer struct {
+	factory          internalinterfaces.SharedInformerFactory
+	tweakListOptions internalinterfaces.TweakListOptionsFunc
+	namespace        string
+}
+
+// NewMyResourceInformer constructs a new informer for MyResource type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewMyResourceInformer(client clientset.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers) cache.SharedIndexInformer {
+	return NewFilteredMyResourceInformer(client, namespace, resyncPeriod, indexers, nil)
+}
+
+// NewFilteredMyResourceInformer constructs a new informer for MyResource type.
+// Always prefer using an informer factory to get a shared informer instead of getting an independent
+// one. This reduces memory footprint and number of connections to the server.
+func NewFilteredMyResourceInformer(client clientset.Interface, namespace string, resyncPeriod time.Duration, indexers cache.Indexers, tweakListOptions internalinterfaces.TweakListOptionsFunc) cache.SharedIndexInformer {
+	return cache.NewSharedIndexInformer(
+		&cache.ListWatch{
+			ListFunc: func(options v1.ListOptions) (runtime.Object, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MygroupV1alpha1().MyResources(namespace).List(context.TODO(), options)
+			},
+			WatchFunc: func(options v1.ListOptions) (watch.Interface, error) {
+				if tweakListOptions != nil {
+					tweakListOptions(&options)
+				}
+				return client.MygroupV1alpha1().MyResources(namespace).Watch(context.TODO(), options)
+			},
+		},
+		&mygroupexamplecomv1alpha1.MyResource{},
+		resyncPeriod,
+		indexers,
+	)
+}
+
+func (f *myResourceInformer) defaultInformer(client clientset.Interface, resyncPeriod time.Duration) cache.SharedIndexInformer {
+	return NewFilteredMyResourceInformer(client, f.namespace, resyncPeriod, cache.Indexers{cache.NamespaceIndex: cache.MetaNamespaceIndexFunc}, f.tweakListOptions)
+}
+
+func (f *myResourceInformer) Informer() cache.SharedIndexInformer {
+	return f.factory.InformerFor(&mygroupexamplecomv1alpha1.MyResource{}, f.defaultInformer)
+}
+
+func (f *myResourceInformer) Lister() v1alpha1.MyResourceLister {
+	return v1alpha1.NewMyResourceLister(f.Informer().GetIndexer())
+}
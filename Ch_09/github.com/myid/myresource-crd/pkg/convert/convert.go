@@ -0,0 +1,114 @@
+// Package convert converts MyResources between their typed and unstructured
+// forms and resolves their resource through a RESTMapper.
+package convert
+
+import (
+	"fmt"
+	"math"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	"github.com/myid/myresource-crd/pkg/clientset/clientset/scheme"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/util/validation/field"
+)
+
+// Kind is the GroupVersionKind of MyResource.
+var Kind = v1alpha1.SchemeGroupVersion.WithKind("MyResource")
+
+// ToUnstructured converts myres to an Unstructured object, with the apiVersion
+// and kind of MyResource.
+func ToUnstructured(myres *v1alpha1.MyResource) (*unstructured.Unstructured, error) {
+	u := &unstructured.Unstructured{}
+	if err := scheme.Scheme.Convert(myres, u, nil); err != nil {
+		return nil, err
+	}
+	u.SetGroupVersionKind(Kind)
+	return u, nil
+}
+
+// FromUnstructured converts u to a MyResource. It fails when u is of another
+// kind or when its quantities are invalid.
+func FromUnstructured(u *unstructured.Unstructured) (*v1alpha1.MyResource, error) {
+	if gvk := u.GroupVersionKind(); gvk != Kind {
+		return nil, fmt.Errorf("%s is not a %s", gvk, Kind)
+	}
+	if err := Validate(u); err != nil {
+		return nil, err
+	}
+	myres := &v1alpha1.MyResource{}
+	if err := scheme.Scheme.Convert(u, myres, nil); err != nil {
+		return nil, err
+	}
+	return myres, nil
+}
+
+// Validate checks the Quantity fields of u, spec.memory, which can be either
+// a string or a number. The error is an Invalid API error, as the API server
+// would return.
+func Validate(u *unstructured.Unstructured) error {
+	var errs field.ErrorList
+	path := field.NewPath("spec", "memory")
+	value, found, err := unstructured.NestedFieldNoCopy(u.Object, "spec", "memory")
+	switch {
+	case err != nil:
+		errs = append(errs, field.Invalid(path.Root(), u.Object["spec"], err.Error()))
+	case !found:
+		errs = append(errs, field.Required(path, ""))
+	default:
+		errs = append(errs, validateQuantity(path, value)...)
+	}
+	if len(errs) > 0 {
+		return apierrors.NewInvalid(Kind.GroupKind(), u.GetName(), errs)
+	}
+	return nil
+}
+
+func validateQuantity(path *field.Path, value interface{}) field.ErrorList {
+	var q resource.Quantity
+	switch value := value.(type) {
+	case string:
+		parsed, err := resource.ParseQuantity(value)
+		if err != nil {
+			return field.ErrorList{field.Invalid(path, value, err.Error())}
+		}
+		q = parsed
+	case int64:
+		q = *resource.NewQuantity(value, resource.BinarySI)
+	case float64:
+		// the milli value must fit in an int64
+		if math.IsNaN(value) || math.Abs(value) >= math.MaxInt64/1000 {
+			return field.ErrorList{field.Invalid(path, value, "out of range")}
+		}
+		q = *resource.NewMilliQuantity(int64(value*1000), resource.DecimalSI)
+	default:
+		return field.ErrorList{field.TypeInvalid(path, value, "must be a string or a number")}
+	}
+	if q.Sign() < 0 {
+		return field.ErrorList{field.Invalid(path, value, "must be greater than or equal to 0")}
+	}
+	return nil
+}
+
+// ResourceFor resolves the resource of the GroupVersionKind gvk with mapper, and
+// tells whether it is namespaced.
+func ResourceFor(mapper meta.RESTMapper, gvk schema.GroupVersionKind) (schema.GroupVersionResource, bool, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if err != nil {
+		return schema.GroupVersionResource{}, false, err
+	}
+	return mapping.Resource, mapping.Scope.Name() == meta.RESTScopeNameNamespace, nil
+}
+
+// NewRESTMapper returns a RESTMapper knowing only MyResource, for when there
+// is no API server to discover it from.
+func NewRESTMapper() meta.RESTMapper {
+	mapper := meta.NewDefaultRESTMapper([]schema.GroupVersion{v1alpha1.SchemeGroupVersion})
+	// the names of the CRD follow the default pluralization of the kind
+	plural, singular := meta.UnsafeGuessKindToResource(Kind)
+	mapper.AddSpecific(Kind, plural, singular, meta.RESTScopeNamespace)
+	return mapper
+}
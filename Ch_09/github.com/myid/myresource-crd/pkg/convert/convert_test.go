@@ -0,0 +1,174 @@
+package convert
+
+import (
+	"testing"
+
+	"github.com/myid/myresource-crd/pkg/apis/mygroup.example.com/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+)
+
+func newUnstructured(memory interface{}) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{Object: map[string]interface{}{
+		"spec": map[string]interface{}{
+			"image": "nginx",
+		},
+	}}
+	if memory != nil {
+		u.Object["spec"].(map[string]interface{})["memory"] = memory
+	}
+	u.SetGroupVersionKind(Kind)
+	u.SetName("myres1")
+	return u
+}
+
+func TestFromUnstructured(t *testing.T) {
+	tests := []struct {
+		name        string
+		u           *unstructured.Unstructured
+		wantMemory  string
+		wantErr     bool
+		wantInvalid bool
+	}{
+		{
+			name:       "case 1: memory as a string",
+			u:          newUnstructured("1024Mi"),
+			wantMemory: "1Gi",
+		},
+		{
+			name:       "case 2: memory as an integer",
+			u:          newUnstructured(int64(1024 * 1024 * 1024)),
+			wantMemory: "1Gi",
+		},
+		{
+			name:        "case 3: invalid memory",
+			u:           newUnstructured("1 gig"),
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name:        "case 4: negative memory",
+			u:           newUnstructured("-1Mi"),
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name:        "case 5: memory of the wrong type",
+			u:           newUnstructured(true),
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name:        "case 6: missing memory",
+			u:           newUnstructured(nil),
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name:       "case 7: memory as a float",
+			u:          newUnstructured(1.5),
+			wantMemory: "1500m",
+		},
+		{
+			name:        "case 8: memory as a float out of range",
+			u:           newUnstructured(1e300),
+			wantErr:     true,
+			wantInvalid: true,
+		},
+		{
+			name: "case 9: another kind",
+			u: func() *unstructured.Unstructured {
+				u := newUnstructured("1Gi")
+				u.SetKind("Pod")
+				return u
+			}(),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := FromUnstructured(tt.u)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("FromUnstructured() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if apierrors.IsInvalid(err) != tt.wantInvalid {
+				t.Errorf("FromUnstructured() error = %v, want an Invalid error %v", err, tt.wantInvalid)
+			}
+			if err != nil {
+				return
+			}
+			if got.Spec.Image != "nginx" {
+				t.Errorf("FromUnstructured() image = %q, want %q", got.Spec.Image, "nginx")
+			}
+			if want := resource.MustParse(tt.wantMemory); got.Spec.Memory.Cmp(want) != 0 {
+				t.Errorf("FromUnstructured() memory = %s, want %s", got.Spec.Memory.String(), tt.wantMemory)
+			}
+		})
+	}
+}
+
+func TestToUnstructured(t *testing.T) {
+	myres := &v1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{Name: "myres1", Namespace: "default"},
+		Spec: v1alpha1.MyResourceSpec{
+			Image:  "nginx",
+			Memory: resource.MustParse("1024Mi"),
+		},
+	}
+	u, err := ToUnstructured(myres)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if u.GroupVersionKind() != Kind {
+		t.Errorf("ToUnstructured() kind = %s, want %s", u.GroupVersionKind(), Kind)
+	}
+	memory, _, _ := unstructured.NestedString(u.Object, "spec", "memory")
+	if memory != "1Gi" {
+		t.Errorf("ToUnstructured() memory = %q, want %q", memory, "1Gi")
+	}
+
+	back, err := FromUnstructured(u)
+	if err != nil {
+		t.Fatal(err)
+	}
+	if back.Name != myres.Name || back.Spec.Memory.Cmp(myres.Spec.Memory) != 0 {
+		t.Errorf("FromUnstructured(ToUnstructured()) = %v, want %v", back, myres)
+	}
+}
+
+func TestResourceFor(t *testing.T) {
+	tests := []struct {
+		name    string
+		gvk     schema.GroupVersionKind
+		want    schema.GroupVersionResource
+		wantErr bool
+	}{
+		{
+			name: "case 1: MyResource",
+			gvk:  Kind,
+			want: v1alpha1.SchemeGroupVersion.WithResource("myresources"),
+		},
+		{
+			name:    "case 2: unknown kind",
+			gvk:     v1alpha1.SchemeGroupVersion.WithKind("OtherResource"),
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, namespaced, err := ResourceFor(NewRESTMapper(), tt.gvk)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ResourceFor() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got != tt.want || !namespaced {
+				t.Errorf("ResourceFor() = %s, %v, want %s, true", got, namespaced, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,186 @@
+// Package resourceclient offers a single code path to work with the objects of
+// any kind, as unstructured objects, through the dynamic client. The resource
+// of the kind and its scope are discovered from the API server.
+package resourceclient
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	"k8s.io/client-go/discovery"
+	"k8s.io/client-go/discovery/cached/memory"
+	"k8s.io/client-go/dynamic"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/restmapper"
+)
+
+// ResourceClient works with the objects of a single kind.
+type ResourceClient struct {
+	client  dynamic.NamespaceableResourceInterface
+	mapping *meta.RESTMapping
+}
+
+// NewDeferredMapper returns a RESTMapper that discovers the API resources
+// the first time it is used, and caches them until it is reset.
+func NewDeferredMapper(discoveryClient discovery.DiscoveryInterface) meta.ResettableRESTMapper {
+	return restmapper.NewDeferredDiscoveryRESTMapper(memory.NewMemCacheClient(discoveryClient))
+}
+
+// NewForConfig returns a ResourceClient for the kind gvk, as accepted by
+// ParseGVK, on the API server of config.
+func NewForConfig(config *rest.Config, gvk string) (*ResourceClient, error) {
+	dynamicClient, err := dynamic.NewForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	discoveryClient, err := discovery.NewDiscoveryClientForConfig(config)
+	if err != nil {
+		return nil, err
+	}
+	return New(dynamicClient, NewDeferredMapper(discoveryClient), gvk)
+}
+
+// New returns a ResourceClient for the kind gvk, as accepted by ParseGVK,
+// resolved with mapper.
+func New(dynamicClient dynamic.Interface, mapper meta.RESTMapper, gvk string) (*ResourceClient, error) {
+	kind, err := ParseGVK(gvk)
+	if err != nil {
+		return nil, err
+	}
+	return NewForKind(dynamicClient, mapper, kind)
+}
+
+// NewForKind returns a ResourceClient for the kind gvk, resolved with mapper.
+// A resettable mapper is reset once when it does not know the kind, in case
+// its CRD has been installed since the resources were discovered.
+func NewForKind(dynamicClient dynamic.Interface, mapper meta.RESTMapper, gvk schema.GroupVersionKind) (*ResourceClient, error) {
+	mapping, err := mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	if resettable, ok := mapper.(meta.ResettableRESTMapper); ok && meta.IsNoMatchError(err) {
+		resettable.Reset()
+		mapping, err = mapper.RESTMapping(gvk.GroupKind(), gvk.Version)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &ResourceClient{
+		client:  dynamicClient.Resource(mapping.Resource),
+		mapping: mapping,
+	}, nil
+}
+
+// ParseGVK parses a kind given either as apiVersion/Kind, for example
+// "mygroup.example.com/v1alpha1/MyResource" or "v1/Pod", or as
+// Kind.version.group, for example "MyResource.v1alpha1.mygroup.example.com".
+func ParseGVK(s string) (schema.GroupVersionKind, error) {
+	if i := strings.LastIndex(s, "/"); i >= 0 {
+		gv, err := schema.ParseGroupVersion(s[:i])
+		if err != nil {
+			return schema.GroupVersionKind{}, err
+		}
+		if gv.Version == "" || s[i+1:] == "" {
+			return schema.GroupVersionKind{}, fmt.Errorf("invalid kind %q, expected apiVersion/Kind", s)
+		}
+		return gv.WithKind(s[i+1:]), nil
+	}
+	gvk, _ := schema.ParseKindArg(s)
+	if gvk == nil {
+		return schema.GroupVersionKind{}, fmt.Errorf("invalid kind %q, expected Kind.version.group", s)
+	}
+	return *gvk, nil
+}
+
+// GroupVersionKind returns the kind of the objects of c.
+func (c *ResourceClient) GroupVersionKind() schema.GroupVersionKind {
+	return c.mapping.GroupVersionKind
+}
+
+// GroupVersionResource returns the resource the kind of c is mapped to.
+func (c *ResourceClient) GroupVersionResource() schema.GroupVersionResource {
+	return c.mapping.Resource
+}
+
+// Namespaced tells whether the objects of c live in a namespace.
+func (c *ResourceClient) Namespaced() bool {
+	return c.mapping.Scope.Name() == meta.RESTScopeNameNamespace
+}
+
+// Get returns the object name of namespace. namespace is ignored for
+// cluster-scoped kinds.
+func (c *ResourceClient) Get(ctx context.Context, namespace, name string, opts metav1.GetOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return c.resource(namespace).Get(ctx, name, opts, subresources...)
+}
+
+// List returns the objects of namespace, or of all the namespaces when it is
+// empty.
+func (c *ResourceClient) List(ctx context.Context, namespace string, opts metav1.ListOptions) (*unstructured.UnstructuredList, error) {
+	return c.resource(namespace).List(ctx, opts)
+}
+
+// Watch watches the objects of namespace, or of all the namespaces when it is
+// empty.
+func (c *ResourceClient) Watch(ctx context.Context, namespace string, opts metav1.ListOptions) (watch.Interface, error) {
+	return c.resource(namespace).Watch(ctx, opts)
+}
+
+// Create creates obj in its namespace, or in the default one when it has none.
+func (c *ResourceClient) Create(ctx context.Context, obj *unstructured.Unstructured, opts metav1.CreateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	namespace, err := c.prepare(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.resource(namespace).Create(ctx, obj, opts, subresources...)
+}
+
+// Update updates obj in its namespace, or in the default one when it has none.
+func (c *ResourceClient) Update(ctx context.Context, obj *unstructured.Unstructured, opts metav1.UpdateOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	namespace, err := c.prepare(obj)
+	if err != nil {
+		return nil, err
+	}
+	return c.resource(namespace).Update(ctx, obj, opts, subresources...)
+}
+
+// Patch patches the object name of namespace with data of the patch type pt.
+func (c *ResourceClient) Patch(ctx context.Context, namespace, name string, pt types.PatchType, data []byte, opts metav1.PatchOptions, subresources ...string) (*unstructured.Unstructured, error) {
+	return c.resource(namespace).Patch(ctx, name, pt, data, opts, subresources...)
+}
+
+// Delete deletes the object name of namespace.
+func (c *ResourceClient) Delete(ctx context.Context, namespace, name string, opts metav1.DeleteOptions, subresources ...string) error {
+	return c.resource(namespace).Delete(ctx, name, opts, subresources...)
+}
+
+func (c *ResourceClient) resource(namespace string) dynamic.ResourceInterface {
+	if !c.Namespaced() {
+		return c.client
+	}
+	return c.client.Namespace(namespace)
+}
+
+// prepare sets the kind of obj when it has none, and returns the namespace
+// to write it to.
+func (c *ResourceClient) prepare(obj *unstructured.Unstructured) (string, error) {
+	gvk := obj.GroupVersionKind()
+	if gvk.Empty() {
+		obj.SetGroupVersionKind(c.mapping.GroupVersionKind)
+	} else if gvk != c.mapping.GroupVersionKind {
+		return "", fmt.Errorf("%s is not a %s", gvk, c.mapping.GroupVersionKind)
+	}
+	if !c.Namespaced() {
+		if obj.GetNamespace() != "" {
+			return "", fmt.Errorf("%s is cluster-scoped, %s cannot have a namespace", c.mapping.GroupVersionKind.Kind, obj.GetName())
+		}
+		return "", nil
+	}
+	if obj.GetNamespace() == "" {
+		obj.SetNamespace(metav1.NamespaceDefault)
+	}
+	return obj.GetNamespace(), nil
+}
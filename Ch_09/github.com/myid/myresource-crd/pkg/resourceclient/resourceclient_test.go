@@ -0,0 +1,256 @@
+package resourceclient
+
+import (
+	"context"
+	"testing"
+
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/apimachinery/pkg/watch"
+	fakediscovery "k8s.io/client-go/discovery/fake"
+	dynamicfake "k8s.io/client-go/dynamic/fake"
+	ktesting "k8s.io/client-go/testing"
+)
+
+var (
+	_myresources = schema.GroupVersionResource{Group: "mygroup.example.com", Version: "v1alpha1", Resource: "myresources"}
+	_namespaces  = schema.GroupVersionResource{Version: "v1", Resource: "namespaces"}
+)
+
+var _myresourcesList = &metav1.APIResourceList{
+	GroupVersion: "mygroup.example.com/v1alpha1",
+	APIResources: []metav1.APIResource{
+		{Name: "myresources", Namespaced: true, Kind: "MyResource", Verbs: metav1.Verbs{"get", "list"}},
+	},
+}
+
+func newFakes(resources ...*metav1.APIResourceList) (*dynamicfake.FakeDynamicClient, *fakediscovery.FakeDiscovery) {
+	dynamicClient := dynamicfake.NewSimpleDynamicClientWithCustomListKinds(runtime.NewScheme(),
+		map[schema.GroupVersionResource]string{
+			_myresources: "MyResourceList",
+			_namespaces:  "NamespaceList",
+		})
+	discoveryClient := &fakediscovery.FakeDiscovery{Fake: &ktesting.Fake{}}
+	discoveryClient.Resources = append([]*metav1.APIResourceList{{
+		GroupVersion: "v1",
+		APIResources: []metav1.APIResource{
+			{Name: "namespaces", Namespaced: false, Kind: "Namespace", Verbs: metav1.Verbs{"get", "list"}},
+		},
+	}}, resources...)
+	return dynamicClient, discoveryClient
+}
+
+func newObject(apiVersion, kind, namespace, name string) *unstructured.Unstructured {
+	u := &unstructured.Unstructured{}
+	u.SetAPIVersion(apiVersion)
+	u.SetKind(kind)
+	u.SetNamespace(namespace)
+	u.SetName(name)
+	return u
+}
+
+func TestParseGVK(t *testing.T) {
+	tests := []struct {
+		name    string
+		s       string
+		want    schema.GroupVersionKind
+		wantErr bool
+	}{
+		{
+			name: "case 1: apiVersion/Kind",
+			s:    "mygroup.example.com/v1alpha1/MyResource",
+			want: _myresources.GroupVersion().WithKind("MyResource"),
+		},
+		{
+			name: "case 2: core apiVersion/Kind",
+			s:    "v1/Namespace",
+			want: schema.GroupVersionKind{Version: "v1", Kind: "Namespace"},
+		},
+		{
+			name: "case 3: Kind.version.group",
+			s:    "MyResource.v1alpha1.mygroup.example.com",
+			want: _myresources.GroupVersion().WithKind("MyResource"),
+		},
+		{
+			name:    "case 4: kind only",
+			s:       "MyResource",
+			wantErr: true,
+		},
+		{
+			name:    "case 5: missing kind",
+			s:       "mygroup.example.com/v1alpha1/",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseGVK(tt.s)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseGVK() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseGVK() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestNew(t *testing.T) {
+	tests := []struct {
+		name           string
+		gvk            string
+		want           schema.GroupVersionResource
+		wantNamespaced bool
+		wantErr        bool
+	}{
+		{
+			name:           "case 1: namespaced",
+			gvk:            "mygroup.example.com/v1alpha1/MyResource",
+			want:           _myresources,
+			wantNamespaced: true,
+		},
+		{
+			name: "case 2: cluster-scoped",
+			gvk:  "v1/Namespace",
+			want: _namespaces,
+		},
+		{
+			name:    "case 3: unknown kind",
+			gvk:     "mygroup.example.com/v1alpha1/OtherResource",
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			dynamicClient, discoveryClient := newFakes(_myresourcesList)
+			got, err := New(dynamicClient, NewDeferredMapper(discoveryClient), tt.gvk)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("New() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			if got.GroupVersionResource() != tt.want {
+				t.Errorf("New() resource = %v, want %v", got.GroupVersionResource(), tt.want)
+			}
+			if got.Namespaced() != tt.wantNamespaced {
+				t.Errorf("New() namespaced = %v, want %v", got.Namespaced(), tt.wantNamespaced)
+			}
+		})
+	}
+}
+
+func TestNew_installedAfterDiscovery(t *testing.T) {
+	dynamicClient, discoveryClient := newFakes()
+	mapper := NewDeferredMapper(discoveryClient)
+	if _, err := New(dynamicClient, mapper, "v1/Namespace"); err != nil {
+		t.Fatal(err)
+	}
+
+	discoveryClient.Resources = append(discoveryClient.Resources, _myresourcesList)
+	if _, err := New(dynamicClient, mapper, "mygroup.example.com/v1alpha1/MyResource"); err != nil {
+		t.Errorf("New() error = %v after the CRD is installed", err)
+	}
+}
+
+func TestResourceClient(t *testing.T) {
+	ctx := context.Background()
+	dynamicClient, discoveryClient := newFakes(_myresourcesList)
+	mapper := NewDeferredMapper(discoveryClient)
+	myresources, err := New(dynamicClient, mapper, "mygroup.example.com/v1alpha1/MyResource")
+	if err != nil {
+		t.Fatal(err)
+	}
+	namespaces, err := New(dynamicClient, mapper, "v1/Namespace")
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// the namespace of the object is defaulted, its kind is set
+	created, err := myresources.Create(ctx, newObject("", "", "", "myres1"), metav1.CreateOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if created.GetNamespace() != metav1.NamespaceDefault || created.GetKind() != "MyResource" {
+		t.Errorf("Create() namespace = %q, kind = %q", created.GetNamespace(), created.GetKind())
+	}
+	if _, err = myresources.Create(ctx, newObject("mygroup.example.com/v1alpha1", "MyResource", "other", "myres2"),
+		metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err = myresources.Create(ctx, newObject("v1", "Namespace", "", "myres3"),
+		metav1.CreateOptions{}); err == nil {
+		t.Error("Create() of another kind should fail")
+	}
+	if _, err = namespaces.Create(ctx, newObject("v1", "Namespace", "default", "other"),
+		metav1.CreateOptions{}); err == nil {
+		t.Error("Create() of a cluster-scoped object with a namespace should fail")
+	}
+	if _, err = namespaces.Create(ctx, newObject("v1", "Namespace", "", "other"),
+		metav1.CreateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+
+	list, err := myresources.List(ctx, "", metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 2 {
+		t.Errorf("# of myresources in all namespaces should be %d but is %d", 2, len(list.Items))
+	}
+	list, err = myresources.List(ctx, "other", metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(list.Items) != 1 {
+		t.Errorf("# of myresources in other should be %d but is %d", 1, len(list.Items))
+	}
+
+	w, err := myresources.Watch(ctx, "default", metav1.ListOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	defer w.Stop()
+
+	patched, err := myresources.Patch(ctx, "default", "myres1", types.MergePatchType,
+		[]byte(`{"spec":{"image":"nginx"}}`), metav1.PatchOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image, _, _ := unstructured.NestedString(patched.Object, "spec", "image"); image != "nginx" {
+		t.Errorf("Patch() image = %q, want %q", image, "nginx")
+	}
+	if event := <-w.ResultChan(); event.Type != watch.Modified {
+		t.Errorf("Watch() event = %s, want %s", event.Type, watch.Modified)
+	}
+
+	if err := unstructured.SetNestedField(patched.Object, "nginx:1.27", "spec", "image"); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := myresources.Update(ctx, patched, metav1.UpdateOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	got, err := myresources.Get(ctx, "default", "myres1", metav1.GetOptions{})
+	if err != nil {
+		t.Fatal(err)
+	}
+	if image, _, _ := unstructured.NestedString(got.Object, "spec", "image"); image != "nginx:1.27" {
+		t.Errorf("Get() image = %q, want %q", image, "nginx:1.27")
+	}
+
+	if err := myresources.Delete(ctx, "default", "myres1", metav1.DeleteOptions{}); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := namespaces.Get(ctx, "ignored", "other", metav1.GetOptions{}); err != nil {
+		t.Errorf("Get() of a cluster-scoped object error = %v", err)
+	}
+
+	for _, action := range dynamicClient.Actions() {
+		if action.GetResource() == _namespaces && action.GetNamespace() != "" {
+			t.Errorf("%s of namespaces in namespace %q", action.GetVerb(), action.GetNamespace())
+		}
+	}
+}
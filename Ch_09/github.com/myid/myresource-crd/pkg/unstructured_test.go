@@ -3,6 +3,7 @@ package main
 import (
 	"testing"
 
+	"github.com/myid/myresource-crd/pkg/convert"
 	"k8s.io/apimachinery/pkg/runtime"
 	"k8s.io/client-go/dynamic/fake"
 	ktesting "k8s.io/client-go/testing"
@@ -28,7 +29,7 @@ func TestCreateMyResourceWhenResourceExists(t *testing.T) {
 		) (handled bool, ret runtime.Object, err error) {
 			return false, nil, nil
 		})
-	_, err = CreateMyResource(dynamicClient, myres)
+	_, err = CreateMyResource(dynamicClient, convert.NewRESTMapper(), myres)
 	if err == nil {
 		t.Error("Error should happen")
 	}
@@ -11,7 +11,38 @@ func (src *MyResource) ConvertTo(dstRaw conversion.Hub) error {
 	// Copy other fields
 	dst.ObjectMeta = src.ObjectMeta
 	dst.Spec.Image = src.Spec.Image
+	dst.Spec.DriftPolicy = v1alpha1.DriftPolicy(src.Spec.DriftPolicy)
+	dst.Spec.Strategy = src.Spec.Strategy
+	dst.Spec.ProgressDeadlineSeconds = src.Spec.ProgressDeadlineSeconds
+	dst.Spec.MinReadySeconds = src.Spec.MinReadySeconds
+	dst.Spec.Paused = src.Spec.Paused
+	dst.Spec.Suspend = src.Spec.Suspend
+	if src.Spec.Canary != nil {
+		dst.Spec.Canary = &v1alpha1.CanarySpec{
+			Replicas: src.Spec.Canary.Replicas,
+			BakeTime: src.Spec.Canary.BakeTime,
+			Timeout:  src.Spec.Canary.Timeout,
+		}
+	}
 	dst.Status.State = src.Status.State
+	dst.Status.Conditions = src.Status.Conditions
+	if src.Status.Canary != nil {
+		dst.Status.Canary = &v1alpha1.CanaryStatus{
+			Image:     src.Status.Canary.Image,
+			Phase:     v1alpha1.CanaryPhase(src.Status.Canary.Phase),
+			StartTime: src.Status.Canary.StartTime,
+			ReadyTime: src.Status.Canary.ReadyTime,
+			Message:   src.Status.Canary.Message,
+		}
+	}
+	for _, issue := range src.Status.PodIssues {
+		dst.Status.PodIssues = append(dst.Status.PodIssues, v1alpha1.PodIssue{
+			Pod:       issue.Pod,
+			Container: issue.Container,
+			Reason:    issue.Reason,
+			Message:   issue.Message,
+		})
+	}
 	return nil
 }
 
@@ -21,6 +52,37 @@ func (dst *MyResource) ConvertFrom(srcRaw conversion.Hub) error {
 	// Copy other fields
 	dst.ObjectMeta = src.ObjectMeta
 	dst.Spec.Image = src.Spec.Image
+	dst.Spec.DriftPolicy = string(src.Spec.DriftPolicy)
+	dst.Spec.Strategy = src.Spec.Strategy
+	dst.Spec.ProgressDeadlineSeconds = src.Spec.ProgressDeadlineSeconds
+	dst.Spec.MinReadySeconds = src.Spec.MinReadySeconds
+	dst.Spec.Paused = src.Spec.Paused
+	dst.Spec.Suspend = src.Spec.Suspend
+	if src.Spec.Canary != nil {
+		dst.Spec.Canary = &CanarySpec{
+			Replicas: src.Spec.Canary.Replicas,
+			BakeTime: src.Spec.Canary.BakeTime,
+			Timeout:  src.Spec.Canary.Timeout,
+		}
+	}
 	dst.Status.State = src.Status.State
+	dst.Status.Conditions = src.Status.Conditions
+	if src.Status.Canary != nil {
+		dst.Status.Canary = &CanaryStatus{
+			Image:     src.Status.Canary.Image,
+			Phase:     string(src.Status.Canary.Phase),
+			StartTime: src.Status.Canary.StartTime,
+			ReadyTime: src.Status.Canary.ReadyTime,
+			Message:   src.Status.Canary.Message,
+		}
+	}
+	for _, issue := range src.Status.PodIssues {
+		dst.Status.PodIssues = append(dst.Status.PodIssues, PodIssue{
+			Pod:       issue.Pod,
+			Container: issue.Container,
+			Reason:    issue.Reason,
+			Message:   issue.Message,
+		})
+	}
 	return nil
 }
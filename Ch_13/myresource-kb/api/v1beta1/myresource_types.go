@@ -17,6 +17,7 @@ limitations under the License.
 package v1beta1
 
 import (
+	appsv1 "k8s.io/api/apps/v1"
 	"k8s.io/apimachinery/pkg/api/resource"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 )
@@ -33,6 +34,63 @@ type MyResourceSpec struct {
 	// Foo string `json:"foo,omitempty"`
 	Image         string            `json:"image"`
 	MemoryRequest resource.Quantity `json:"memoryRequest"`
+
+	// DriftPolicy controls how manual edits to the owned Deployment are handled.
+	// +kubebuilder:validation:Enum=Enforce;Detect;Preserve
+	// +optional
+	DriftPolicy string `json:"driftPolicy,omitempty"`
+
+	// Strategy is the rollout strategy of the owned Deployment (RollingUpdate or Recreate).
+	// +optional
+	Strategy *appsv1.DeploymentStrategy `json:"strategy,omitempty"`
+
+	// ProgressDeadlineSeconds is the maximum time in seconds for the owned Deployment
+	// to make progress before the rollout is reported as stalled.
+	// +kubebuilder:validation:Minimum=1
+	// +optional
+	ProgressDeadlineSeconds *int32 `json:"progressDeadlineSeconds,omitempty"`
+
+	// MinReadySeconds is the minimum number of seconds a new Pod should be ready
+	// without any of its containers crashing to be considered available.
+	// +kubebuilder:validation:Minimum=0
+	// +optional
+	MinReadySeconds int32 `json:"minReadySeconds,omitempty"`
+
+	// Canary enables canary rollouts of a new image.
+	// +optional
+	Canary *CanarySpec `json:"canary,omitempty"`
+
+	// Paused stops the reconciliation of this MyResource without deleting anything,
+	// so the owned Deployment can be changed by hand without the operator reverting it.
+	// +optional
+	Paused bool `json:"paused,omitempty"`
+
+	// Suspend scales the owned Deployment to zero replicas while keeping its configuration.
+	// +optional
+	Suspend bool `json:"suspend,omitempty"`
+}
+
+// CanarySpec configures canary rollouts of a new image.
+type CanarySpec struct {
+	// Replicas is the number of replicas of the canary Deployment. It is a count of
+	// Pods, not a share of the total: during the rollout, the primary Deployment keeps
+	// its single replica running the previous image next to them. The canary
+	// Deployment follows spec.driftPolicy like the primary one.
+	// +kubebuilder:validation:Minimum=1
+	// +kubebuilder:default=1
+	// +optional
+	Replicas int32 `json:"replicas,omitempty"`
+
+	// BakeTime is how long the canary Deployment must stay ready before it is promoted.
+	// +kubebuilder:default="5m"
+	// +optional
+	BakeTime metav1.Duration `json:"bakeTime,omitempty"`
+
+	// Timeout is how long the canary Deployment may take to become ready before
+	// it is rolled back.
+	// +kubebuilder:default="10m"
+	// +optional
+	Timeout metav1.Duration `json:"timeout,omitempty"`
 }
 
 // MyResourceStatus defines the observed state of MyResource
@@ -40,6 +98,59 @@ type MyResourceStatus struct {
 	// INSERT ADDITIONAL STATUS FIELD - define observed state of cluster
 	// Important: Run "make" to regenerate code after modifying this file
 	State string `json:"state"`
+
+	// Conditions represent the latest available observations of the MyResource state.
+	// +listType=map
+	// +listMapKey=type
+	// +optional
+	Conditions []metav1.Condition `json:"conditions,omitempty"`
+
+	// Canary reports the progress of the latest canary rollout.
+	// +optional
+	Canary *CanaryStatus `json:"canary,omitempty"`
+
+	// PodIssues lists why the Pods of the current rollout are not healthy,
+	// e.g. CrashLoopBackOff or Unschedulable.
+	// +kubebuilder:validation:MaxItems=10
+	// +optional
+	PodIssues []PodIssue `json:"podIssues,omitempty"`
+}
+
+// PodIssue describes a problem of a Pod run by the MyResource.
+type PodIssue struct {
+	// Pod is the name of the Pod.
+	Pod string `json:"pod"`
+
+	// Container is the name of the affected container, empty for Pod-level issues.
+	// +optional
+	Container string `json:"container,omitempty"`
+
+	// Reason is one of CrashLoopBackOff, ImagePullBackOff, ErrImagePull, OOMKilled or Unschedulable.
+	Reason string `json:"reason"`
+
+	// Message is the detail reported by the kubelet or the scheduler.
+	// +optional
+	Message string `json:"message,omitempty"`
+}
+
+// CanaryStatus describes the latest canary rollout.
+type CanaryStatus struct {
+	// Image is the image rolled out by the canary.
+	Image string `json:"image"`
+
+	// Phase is the current step of the canary rollout.
+	Phase string `json:"phase"`
+
+	// StartTime is when the canary Deployment was created.
+	StartTime metav1.Time `json:"startTime"`
+
+	// ReadyTime is when the canary Deployment became ready, the bake time counts from there.
+	// +optional
+	ReadyTime *metav1.Time `json:"readyTime,omitempty"`
+
+	// Message is a human readable description of the last step.
+	// +optional
+	Message string `json:"message,omitempty"`
 }
 
 // +kubebuilder:object:root=true
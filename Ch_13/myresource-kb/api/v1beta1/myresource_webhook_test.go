@@ -17,16 +17,110 @@ limitations under the License.
 package v1beta1
 
 import (
+	"time"
+
 	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
 )
 
 var _ = Describe("MyResource Webhook", func() {
 
 	Context("When creating MyResource under Conversion Webhook", func() {
-		It("Should get the converted version of MyResource", func() {
+		const namespace = "default"
+
+		It("Should convert a v1beta1 MyResource to the v1alpha1 storage version", func() {
+			key := types.NamespacedName{Namespace: namespace, Name: "from-v1beta1"}
+			created := &MyResource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				Spec: MyResourceSpec{
+					Image:                   "nginx:1.27",
+					MemoryRequest:           resource.MustParse("256Mi"),
+					DriftPolicy:             "Detect",
+					ProgressDeadlineSeconds: ptr.To(int32(120)),
+					Suspend:                 true,
+					Canary: &CanarySpec{
+						Replicas: 2,
+						BakeTime: metav1.Duration{Duration: 3 * time.Minute},
+						Timeout:  metav1.Duration{Duration: 6 * time.Minute},
+					},
+				},
+			}
+			Expect(k8sClient.Create(ctx, created)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, created)
+
+			stored := &mygroupv1alpha1.MyResource{}
+			Expect(k8sClient.Get(ctx, key, stored)).To(Succeed())
+			Expect(stored.Spec.Image).To(Equal("nginx:1.27"))
+			Expect(stored.Spec.Memory.Equal(resource.MustParse("256Mi"))).To(BeTrue())
+			Expect(stored.Spec.DriftPolicy).To(Equal(mygroupv1alpha1.DriftPolicyDetect))
+			Expect(stored.Spec.ProgressDeadlineSeconds).To(Equal(ptr.To(int32(120))))
+			Expect(stored.Spec.Suspend).To(BeTrue())
+			Expect(stored.Spec.Canary).NotTo(BeNil())
+			Expect(stored.Spec.Canary.Replicas).To(Equal(int32(2)))
+			Expect(stored.Spec.Canary.BakeTime.Duration).To(Equal(3 * time.Minute))
+		})
+
+		It("Should convert a v1alpha1 MyResource to v1beta1", func() {
+			key := types.NamespacedName{Namespace: namespace, Name: "from-v1alpha1"}
+			created := &mygroupv1alpha1.MyResource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				Spec: mygroupv1alpha1.MyResourceSpec{
+					Image:           "nginx:1.27",
+					Memory:          resource.MustParse("1Gi"),
+					DriftPolicy:     mygroupv1alpha1.DriftPolicyPreserve,
+					MinReadySeconds: 10,
+					Paused:          true,
+				},
+			}
+			Expect(k8sClient.Create(ctx, created)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, created)
+
+			served := &MyResource{}
+			Expect(k8sClient.Get(ctx, key, served)).To(Succeed())
+			Expect(served.Spec.Image).To(Equal("nginx:1.27"))
+			Expect(served.Spec.MemoryRequest.Equal(resource.MustParse("1Gi"))).To(BeTrue())
+			Expect(served.Spec.DriftPolicy).To(Equal("Preserve"))
+			Expect(served.Spec.MinReadySeconds).To(Equal(int32(10)))
+			Expect(served.Spec.Paused).To(BeTrue())
+		})
+
+		It("Should keep the status through an update as v1beta1", func() {
+			key := types.NamespacedName{Namespace: namespace, Name: "status-roundtrip"}
+			created := &mygroupv1alpha1.MyResource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+				Spec: mygroupv1alpha1.MyResourceSpec{
+					Image:  "nginx:1.27",
+					Memory: resource.MustParse("128Mi"),
+				},
+			}
+			Expect(k8sClient.Create(ctx, created)).To(Succeed())
+			DeferCleanup(k8sClient.Delete, created)
+			created.Status = mygroupv1alpha1.MyResourceStatus{
+				State: "Building",
+				PodIssues: []mygroupv1alpha1.PodIssue{
+					{Pod: "p1", Container: "main", Reason: "CrashLoopBackOff"},
+				},
+			}
+			Expect(k8sClient.Status().Update(ctx, created)).To(Succeed())
 
-			// TODO(user): Add your logic here
+			served := &MyResource{}
+			Expect(k8sClient.Get(ctx, key, served)).To(Succeed())
+			Expect(served.Status.PodIssues).To(HaveLen(1))
+			served.Spec.MemoryRequest = resource.MustParse("512Mi")
+			Expect(k8sClient.Update(ctx, served)).To(Succeed())
 
+			stored := &mygroupv1alpha1.MyResource{}
+			Expect(k8sClient.Get(ctx, key, stored)).To(Succeed())
+			Expect(stored.Spec.Memory.Equal(resource.MustParse("512Mi"))).To(BeTrue())
+			Expect(stored.Status.State).To(Equal("Building"))
+			Expect(stored.Status.PodIssues).To(HaveLen(1))
 		})
 	})
 
@@ -17,18 +17,33 @@ limitations under the License.
 package main
 
 import (
+	"context"
 	"crypto/tls"
 	"flag"
 	"os"
+	"strings"
+	"time"
 
 	// Import all Kubernetes client auth plugins (e.g. Azure, GCP, OIDC, etc.)
 	// to ensure that exec-entrypoint and run can make use of them.
 	_ "k8s.io/client-go/plugin/pkg/client/auth"
 
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/labels"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/selection"
+	"k8s.io/apimachinery/pkg/types"
 	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
 	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/utils/ptr"
 	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/cache"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/config"
 	"sigs.k8s.io/controller-runtime/pkg/healthz"
 	"sigs.k8s.io/controller-runtime/pkg/log/zap"
 	"sigs.k8s.io/controller-runtime/pkg/metrics/filters"
@@ -37,7 +52,12 @@ import (
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
 	mygroupv1beta1 "github.com/myid/myresource/api/v1beta1"
+	"github.com/myid/myresource/internal/certrotator"
+	managerconfig "github.com/myid/myresource/internal/config"
 	"github.com/myid/myresource/internal/controller"
+	"github.com/myid/myresource/internal/migration"
+	"github.com/myid/myresource/internal/sharding"
+	"github.com/myid/myresource/internal/tracing"
 	// +kubebuilder:scaffold:imports
 )
 
@@ -46,8 +66,30 @@ var (
 	setupLog = ctrl.Log.WithName("setup")
 )
 
+const (
+	leaderElectionID = "ab35bae8.myid.dev"
+
+	shardLeaseDuration = 15 * time.Second
+	shardRenewInterval = 5 * time.Second
+
+	configReloadInterval           = 10 * time.Second
+	defaultMaxConcurrentReconciles = 1
+
+	crdName           = "myresources.mygroup.myid.dev"
+	certCAValidity    = 10 * 365 * 24 * time.Hour
+	certValidity      = 90 * 24 * time.Hour
+	certRotateBefore  = 30 * 24 * time.Hour
+	certCheckInterval = time.Hour
+
+	tracingShutdownTimeout = 5 * time.Second
+
+	storageMigrationCheckpoint    = "myresource-storage-migration"
+	storageMigrationRetryInterval = time.Minute
+)
+
 func init() {
 	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
 
 	utilruntime.Must(mygroupv1alpha1.AddToScheme(scheme))
 	utilruntime.Must(mygroupv1beta1.AddToScheme(scheme))
@@ -60,6 +102,23 @@ func main() {
 	var probeAddr string
 	var secureMetrics bool
 	var enableHTTP2 bool
+	var watchNamespaces string
+	var enableSharding bool
+	var shardLeaseNamespace string
+	var configFile string
+	var enableWebhooks bool
+	var maxConcurrentReconciles int
+	var requeueInterval time.Duration
+	var enableCertRotation bool
+	var webhookCertDir string
+	var webhookPort int
+	var webhookServiceName string
+	var webhookSecretName string
+	var otlpEndpoint string
+	var otlpInsecure bool
+	var traceStdout bool
+	var enableStorageMigration bool
+	var storageMigrationPageSize int64
 	var tlsOpts []func(*tls.Config)
 	flag.StringVar(&metricsAddr, "metrics-bind-address", "0", "The address the metrics endpoint binds to. "+
 		"Use :8443 for HTTPS or :8080 for HTTP, or leave as 0 to disable the metrics service.")
@@ -71,14 +130,89 @@ func main() {
 		"If set, the metrics endpoint is served securely via HTTPS. Use --metrics-secure=false to use HTTP instead.")
 	flag.BoolVar(&enableHTTP2, "enable-http2", false,
 		"If set, HTTP/2 will be enabled for the metrics and webhook servers")
+	flag.StringVar(&watchNamespaces, "watch-namespaces", "",
+		"Comma-separated list of namespaces the manager watches. Leave empty to watch all namespaces, "+
+			"which requires cluster-wide RBAC. See config/rbac/namespaced for the namespace-scoped variant.")
+	flag.BoolVar(&enableSharding, "enable-sharding", false,
+		"If set, every replica of the manager reconciles its own share of the MyResources, "+
+			"coordinated through Leases, instead of a single elected leader reconciling all of them.")
+	flag.StringVar(&shardLeaseNamespace, "shard-lease-namespace", "",
+		"The namespace holding the shard Leases. Defaults to the namespace the manager runs in.")
+	flag.StringVar(&configFile, "config", "",
+		"Path to a ControllerManagerConfig file. Flags set on the command line override the file. "+
+			"Changes to logLevel and controller are applied without a restart.")
+	flag.BoolVar(&enableWebhooks, "enable-webhooks", os.Getenv("ENABLE_WEBHOOKS") != "false",
+		"If set, the conversion webhook is registered. Defaults to false when ENABLE_WEBHOOKS=false.")
+	flag.BoolVar(&enableCertRotation, "enable-cert-rotation", false,
+		"If set, the manager generates and renews the webhook certificates itself instead of relying on "+
+			"cert-manager, and injects the CA into the CRD conversion webhook.")
+	flag.StringVar(&webhookCertDir, "webhook-cert-dir", "/tmp/k8s-webhook-server/serving-certs",
+		"The directory the webhook server reads tls.crt and tls.key from.")
+	flag.IntVar(&webhookPort, "webhook-port", webhook.DefaultPort,
+		"The port the webhook server listens on.")
+	flag.StringVar(&webhookServiceName, "webhook-service-name", "myresource-kb-webhook-service",
+		"The name of the webhook Service, used for the DNS names of the rotated serving certificate.")
+	flag.StringVar(&webhookSecretName, "webhook-secret-name", "webhook-server-cert",
+		"The name of the Secret holding the rotated webhook certificates.")
+	flag.IntVar(&maxConcurrentReconciles, "max-concurrent-reconciles", defaultMaxConcurrentReconciles,
+		"The maximum number of MyResources reconciled at once.")
+	flag.DurationVar(&requeueInterval, "requeue-interval", 0,
+		"How often a MyResource is reconciled again after a successful reconcile. 0 only reconciles on changes.")
+	flag.StringVar(&otlpEndpoint, "otlp-endpoint", "",
+		"The host:port of the OTLP gRPC collector the reconcile traces are sent to. Leave empty to disable tracing.")
+	flag.BoolVar(&otlpInsecure, "otlp-insecure", false,
+		"If set, the traces are sent to the OTLP collector without TLS.")
+	flag.BoolVar(&traceStdout, "trace-stdout", false,
+		"If set, the reconcile traces are written to stdout instead of an OTLP collector.")
+	flag.BoolVar(&enableStorageMigration, "enable-storage-migration", false,
+		"If set, the elected manager rewrites the stored MyResources in the storage version of the CRD, "+
+			"then removes the other versions from its status.storedVersions.")
+	flag.Int64Var(&storageMigrationPageSize, "storage-migration-page-size", migration.DefaultPageSize,
+		"The number of MyResources migrated at once, the progress is saved after each page.")
 	opts := zap.Options{
 		Development: true,
 	}
 	opts.BindFlags(flag.CommandLine)
 	flag.Parse()
 
+	// the flags set on the command line are kept when the file is (re)loaded
+	explicitFlags := map[string]bool{}
+	flag.Visit(func(f *flag.Flag) {
+		explicitFlags[f.Name] = true
+	})
+	var fileConfig *managerconfig.ControllerManagerConfig
+	var configErr error
+	if configFile != "" {
+		fileConfig, configErr = managerconfig.Load(configFile)
+		if configErr == nil {
+			configErr = fileConfig.ApplyTo(flag.CommandLine)
+		}
+	}
+
+	// an atomic level lets the configuration file change the log level at
+	// runtime, and the level without --zap-log-level is restored when logLevel
+	// is removed from the file
+	defaultLogLevel := zapcore.InfoLevel
+	if opts.Development {
+		defaultLogLevel = zapcore.DebugLevel
+	}
+	logLevel, ok := opts.Level.(uberzap.AtomicLevel)
+	if !ok {
+		logLevel = uberzap.NewAtomicLevelAt(defaultLogLevel)
+		opts.Level = logLevel
+	}
 	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
 
+	if configErr != nil {
+		setupLog.Error(configErr, "unable to load configuration file", "path", configFile)
+		os.Exit(1)
+	}
+	if maxConcurrentReconciles < 1 || maxConcurrentReconciles > managerconfig.MaxConcurrentReconcilesLimit {
+		setupLog.Error(nil, "--max-concurrent-reconciles is out of range",
+			"value", maxConcurrentReconciles, "limit", managerconfig.MaxConcurrentReconcilesLimit)
+		os.Exit(1)
+	}
+
 	// if the enable-http2 flag is false (the default), http/2 should be disabled
 	// due to its vulnerabilities. More specifically, disabling http/2 will
 	// prevent from being vulnerable to the HTTP/2 Stream Cancellation and
@@ -95,6 +229,8 @@ func main() {
 	}
 
 	webhookServer := webhook.NewServer(webhook.Options{
+		Port:    webhookPort,
+		CertDir: webhookCertDir,
 		TLSOpts: tlsOpts,
 	})
 
@@ -122,13 +258,49 @@ func main() {
 		metricsServerOptions.FilterProvider = filters.WithAuthenticationAndAuthorization
 	}
 
-	mgr, err := ctrl.NewManager(ctrl.GetConfigOrDie(), ctrl.Options{
+	namespaces := parseNamespaces(watchNamespaces)
+	if len(namespaces) > 0 {
+		setupLog.Info("watching namespaces", "namespaces", namespaces)
+	}
+	cacheOptions := newCacheOptions(namespaces)
+
+	// With sharding enabled every replica runs the controller, leader election
+	// only applies to the remaining runnables.
+	controllerOptions := config.Controller{}
+	if enableSharding {
+		controllerOptions.NeedLeaderElection = ptr.To(false)
+	}
+
+	ctx := ctrl.SetupSignalHandler()
+
+	tracingOptions := tracing.Options{
+		ServiceName: "myresource-controller-manager",
+		Endpoint:    otlpEndpoint,
+		Insecure:    otlpInsecure,
+	}
+	if traceStdout {
+		tracingOptions.Stdout = os.Stdout
+	}
+	shutdownTracing, err := tracing.Setup(ctx, tracingOptions)
+	if err != nil {
+		setupLog.Error(err, "unable to set up tracing")
+		os.Exit(1)
+	}
+	restConfig := ctrl.GetConfigOrDie()
+	if tracingOptions.Enabled() {
+		setupLog.Info("tracing enabled", "endpoint", otlpEndpoint, "stdout", traceStdout)
+		tracing.WrapConfig(restConfig)
+	}
+
+	mgr, err := ctrl.NewManager(restConfig, ctrl.Options{
 		Scheme:                 scheme,
+		Cache:                  cacheOptions,
+		Controller:             controllerOptions,
 		Metrics:                metricsServerOptions,
 		WebhookServer:          webhookServer,
 		HealthProbeBindAddress: probeAddr,
 		LeaderElection:         enableLeaderElection,
-		LeaderElectionID:       "ab35bae8.myid.dev",
+		LeaderElectionID:       leaderElectionID,
 		// LeaderElectionReleaseOnCancel defines if the leader should step down voluntarily
 		// when the Manager ends. This requires the binary to immediately end when the
 		// Manager is stopped, otherwise, this setting is unsafe. Setting this significantly
@@ -146,14 +318,50 @@ func main() {
 		os.Exit(1)
 	}
 
+	var sharder *sharding.Sharder
+	if enableSharding {
+		sharder, err = newSharder(mgr, shardLeaseNamespace)
+		if err != nil {
+			setupLog.Error(err, "unable to set up sharding")
+			os.Exit(1)
+		}
+		if err = mgr.Add(sharder); err != nil {
+			setupLog.Error(err, "unable to add sharder")
+			os.Exit(1)
+		}
+	}
+
+	// without a configuration file the concurrency cannot change, so the
+	// controller only needs as many workers as reconciles allowed
+	tunables := controller.NewTunables(maxConcurrentReconciles)
+	if fileConfig != nil {
+		tunables = controller.NewTunables(managerconfig.MaxConcurrentReconcilesLimit)
+		if err = mgr.Add(&managerconfig.Watcher{
+			Path:     configFile,
+			Interval: configReloadInterval,
+			Current:  fileConfig,
+			OnChange: func(ctx context.Context, cfg *managerconfig.ControllerManagerConfig) {
+				reloadConfig(ctx, cfg, explicitFlags, maxConcurrentReconciles, requeueInterval,
+					logLevel, defaultLogLevel, tunables)
+			},
+		}); err != nil {
+			setupLog.Error(err, "unable to add configuration watcher")
+			os.Exit(1)
+		}
+	}
+	tunables.Set(maxConcurrentReconciles, requeueInterval)
+
 	if err = (&controller.MyResourceReconciler{
-		Client: mgr.GetClient(),
-		Scheme: mgr.GetScheme(),
+		Client:   mgr.GetClient(),
+		Scheme:   mgr.GetScheme(),
+		Recorder: mgr.GetEventRecorderFor("myresource-controller"),
+		Sharder:  sharder,
+		Tunables: tunables,
 	}).SetupWithManager(mgr); err != nil {
 		setupLog.Error(err, "unable to create controller", "controller", "MyResource")
 		os.Exit(1)
 	}
-	if os.Getenv("ENABLE_WEBHOOKS") != "false" {
+	if enableWebhooks {
 		if err = (&mygroupv1beta1.MyResource{}).SetupWebhookWithManager(mgr); err != nil {
 			setupLog.Error(err, "unable to create webhook", "webhook", "MyResource")
 			os.Exit(1)
@@ -161,6 +369,26 @@ func main() {
 	}
 	// +kubebuilder:scaffold:builder
 
+	if enableWebhooks && enableCertRotation {
+		rotator := newCertRotator(mgr, webhookCertDir, webhookServiceName, webhookSecretName)
+		// the webhook server needs its certificates before the manager starts it
+		if err = rotator.Ensure(ctx); err != nil {
+			setupLog.Error(err, "unable to provision webhook certificates")
+			os.Exit(1)
+		}
+		if err = mgr.Add(rotator); err != nil {
+			setupLog.Error(err, "unable to add certificate rotator")
+			os.Exit(1)
+		}
+	}
+
+	if enableStorageMigration {
+		if err = mgr.Add(newMigrator(mgr, storageMigrationPageSize)); err != nil {
+			setupLog.Error(err, "unable to add storage migrator")
+			os.Exit(1)
+		}
+	}
+
 	if err := mgr.AddHealthzCheck("healthz", healthz.Ping); err != nil {
 		setupLog.Error(err, "unable to set up health check")
 		os.Exit(1)
@@ -171,8 +399,151 @@ func main() {
 	}
 
 	setupLog.Info("starting manager")
-	if err := mgr.Start(ctrl.SetupSignalHandler()); err != nil {
+	if err := mgr.Start(ctx); err != nil {
 		setupLog.Error(err, "problem running manager")
 		os.Exit(1)
 	}
+	// the signal context is done, flush the remaining spans with a fresh one
+	flushCtx, cancel := context.WithTimeout(context.Background(), tracingShutdownTimeout)
+	defer cancel()
+	if err := shutdownTracing(flushCtx); err != nil {
+		setupLog.Error(err, "unable to flush traces")
+	}
+}
+
+// reloadConfig applies the reloadable settings of a changed configuration file.
+// The settings given on the command line, passed as maxConcurrent and interval,
+// keep precedence; fields removed from the file go back to the flag defaults,
+// defaultLogLevel for logLevel.
+func reloadConfig(ctx context.Context, cfg *managerconfig.ControllerManagerConfig, explicitFlags map[string]bool,
+	maxConcurrent int, interval time.Duration, logLevel uberzap.AtomicLevel, defaultLogLevel zapcore.Level,
+	tunables *controller.Tunables) {
+	if !explicitFlags["zap-log-level"] {
+		level := defaultLogLevel
+		if cfg.LogLevel != "" {
+			// the level has been validated when loading the file
+			level, _ = managerconfig.ParseLogLevel(cfg.LogLevel)
+		}
+		logLevel.SetLevel(level)
+	}
+	if !explicitFlags["max-concurrent-reconciles"] {
+		maxConcurrent = cfg.Controller.MaxConcurrentReconcilesOrDefault(defaultMaxConcurrentReconciles)
+	}
+	if !explicitFlags["requeue-interval"] {
+		interval = cfg.Controller.RequeueIntervalOrDefault(0)
+	}
+	tunables.Set(maxConcurrent, interval)
+	ctrl.LoggerFrom(ctx).Info("controller settings reloaded", "logLevel", logLevel.Level(),
+		"maxConcurrentReconciles", maxConcurrent, "requeueInterval", interval)
+}
+
+// newCacheOptions restricts the cache to namespaces, when given, which allows
+// the manager to run with Roles bound in those namespaces instead of
+// ClusterRoles. The Pods and ReplicaSets are only cached when they belong to a
+// MyResource, through the myresource label, rather than all of the cluster.
+func newCacheOptions(namespaces []string) cache.Options {
+	owned, err := labels.NewRequirement("myresource", selection.Exists, nil)
+	utilruntime.Must(err)
+	ownedOnly := cache.ByObject{Label: labels.NewSelector().Add(*owned)}
+	cacheOptions := cache.Options{
+		ByObject: map[client.Object]cache.ByObject{
+			&corev1.Pod{}:        ownedOnly,
+			&appsv1.ReplicaSet{}: ownedOnly,
+		},
+	}
+	if len(namespaces) > 0 {
+		cacheOptions.DefaultNamespaces = make(map[string]cache.Config, len(namespaces))
+		for _, ns := range namespaces {
+			cacheOptions.DefaultNamespaces[ns] = cache.Config{}
+		}
+	}
+	return cacheOptions
+}
+
+// parseNamespaces splits the comma-separated --watch-namespaces value,
+// dropping blanks and duplicates.
+func parseNamespaces(value string) []string {
+	var namespaces []string
+	seen := map[string]bool{}
+	for _, ns := range strings.Split(value, ",") {
+		ns = strings.TrimSpace(ns)
+		if ns == "" || seen[ns] {
+			continue
+		}
+		seen[ns] = true
+		namespaces = append(namespaces, ns)
+	}
+	return namespaces
+}
+
+// newSharder builds the sharder of this replica, identified by its hostname,
+// which is the Pod name when running in the cluster.
+func newSharder(mgr ctrl.Manager, namespace string) (*sharding.Sharder, error) {
+	identity, err := os.Hostname()
+	if err != nil {
+		return nil, err
+	}
+	if namespace == "" {
+		namespace = inClusterNamespace()
+	}
+	setupLog.Info("sharding enabled", "identity", identity, "namespace", namespace)
+	return &sharding.Sharder{
+		Client:        mgr.GetClient(),
+		Reader:        mgr.GetAPIReader(),
+		Namespace:     namespace,
+		Group:         leaderElectionID,
+		Identity:      identity,
+		LeaseDuration: shardLeaseDuration,
+		RenewInterval: shardRenewInterval,
+	}, nil
+}
+
+// newCertRotator builds the rotator of the webhook certificates of the Service
+// in the namespace the manager runs in. Only the conversion webhook of the CRD
+// trusts the rotated CA: the manager serves no mutating or validating webhook,
+// their configurations must be added to MutatingWebhooks and
+// ValidatingWebhooks, and to the RBAC of config/certrotator, when it does.
+func newCertRotator(mgr ctrl.Manager, certDir, serviceName, secretName string) *certrotator.Rotator {
+	namespace := inClusterNamespace()
+	setupLog.Info("webhook certificate rotation enabled", "secret", secretName, "namespace", namespace)
+	return &certrotator.Rotator{
+		Client:  mgr.GetClient(),
+		Reader:  mgr.GetAPIReader(),
+		Secret:  types.NamespacedName{Namespace: namespace, Name: secretName},
+		CertDir: certDir,
+		DNSNames: []string{
+			serviceName + "." + namespace + ".svc",
+			serviceName + "." + namespace + ".svc.cluster.local",
+		},
+		CRDs:          []string{crdName},
+		CAValidity:    certCAValidity,
+		CertValidity:  certValidity,
+		RotateBefore:  certRotateBefore,
+		CheckInterval: certCheckInterval,
+	}
+}
+
+// newMigrator builds the migrator of the stored MyResources, which keeps its
+// checkpoint in the namespace the manager runs in.
+func newMigrator(mgr ctrl.Manager, pageSize int64) *migration.Migrator {
+	namespace := inClusterNamespace()
+	setupLog.Info("storage migration enabled", "checkpoint", storageMigrationCheckpoint, "namespace", namespace)
+	return &migration.Migrator{
+		Client:        mgr.GetClient(),
+		Reader:        mgr.GetAPIReader(),
+		CRD:           crdName,
+		Checkpoint:    types.NamespacedName{Namespace: namespace, Name: storageMigrationCheckpoint},
+		PageSize:      pageSize,
+		RetryInterval: storageMigrationRetryInterval,
+	}
+}
+
+// inClusterNamespace returns the namespace of the service account the manager
+// runs with, or "default" when running outside of a cluster.
+func inClusterNamespace() string {
+	data, err := os.ReadFile("/var/run/secrets/kubernetes.io/serviceaccount/namespace")
+	if err != nil {
+		return "default"
+	}
+	return strings.TrimSpace(string(data))
 }
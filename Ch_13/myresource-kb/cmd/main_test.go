@@ -0,0 +1,115 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package main
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	uberzap "go.uber.org/zap"
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/labels"
+	"k8s.io/utils/ptr"
+
+	managerconfig "github.com/myid/myresource/internal/config"
+	"github.com/myid/myresource/internal/controller"
+)
+
+func Test_reloadConfig(t *testing.T) {
+	tests := []struct {
+		name          string
+		explicitFlags map[string]bool
+		steps         []managerconfig.ControllerManagerConfig
+		wantLevel     zapcore.Level
+		wantInterval  time.Duration
+	}{
+		{
+			name:      "case 1: level of the file",
+			steps:     []managerconfig.ControllerManagerConfig{{LogLevel: "error"}},
+			wantLevel: zapcore.ErrorLevel,
+		},
+		{
+			name: "case 2: level removed from the file, default restored",
+			steps: []managerconfig.ControllerManagerConfig{
+				{LogLevel: "error"},
+				{},
+			},
+			wantLevel: zapcore.InfoLevel,
+		},
+		{
+			name:          "case 3: level given on the command line kept",
+			explicitFlags: map[string]bool{"zap-log-level": true},
+			steps: []managerconfig.ControllerManagerConfig{
+				{LogLevel: "error"},
+				{},
+			},
+			wantLevel: zapcore.DebugLevel,
+		},
+		{
+			name: "case 4: requeue interval removed from the file, default restored",
+			steps: []managerconfig.ControllerManagerConfig{
+				{Controller: managerconfig.ControllerConfig{RequeueInterval: &metav1.Duration{Duration: time.Minute}}},
+				{Controller: managerconfig.ControllerConfig{MaxConcurrentReconciles: ptr.To(2)}},
+			},
+			wantLevel: zapcore.InfoLevel,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			// the level set by --zap-log-level=debug or the default one
+			logLevel := uberzap.NewAtomicLevelAt(zapcore.DebugLevel)
+			if !tt.explicitFlags["zap-log-level"] {
+				logLevel.SetLevel(zapcore.InfoLevel)
+			}
+			tunables := controller.NewTunables(managerconfig.MaxConcurrentReconcilesLimit)
+			for i := range tt.steps {
+				reloadConfig(context.Background(), &tt.steps[i], tt.explicitFlags,
+					defaultMaxConcurrentReconciles, 0, logLevel, zapcore.InfoLevel, tunables)
+			}
+			if got := logLevel.Level(); got != tt.wantLevel {
+				t.Errorf("log level = %v, want %v", got, tt.wantLevel)
+			}
+			if got := tunables.RequeueInterval(); got != tt.wantInterval {
+				t.Errorf("requeue interval = %v, want %v", got, tt.wantInterval)
+			}
+		})
+	}
+}
+
+func Test_newCacheOptions(t *testing.T) {
+	owned := labels.Set{"myresource": "test"}
+	other := labels.Set{"app": "other"}
+
+	opts := newCacheOptions([]string{"team-a"})
+	if _, ok := opts.DefaultNamespaces["team-a"]; !ok || len(opts.DefaultNamespaces) != 1 {
+		t.Errorf("DefaultNamespaces = %v, want team-a only", opts.DefaultNamespaces)
+	}
+	for obj, byObject := range opts.ByObject {
+		if byObject.Label == nil || !byObject.Label.Matches(owned) || byObject.Label.Matches(other) {
+			t.Errorf("%T is cached with the label selector %v, want myresource only", obj, byObject.Label)
+		}
+	}
+	if len(opts.ByObject) != 2 {
+		t.Errorf("# of restricted types should be %d but is %d", 2, len(opts.ByObject))
+	}
+
+	if opts := newCacheOptions(nil); opts.DefaultNamespaces != nil {
+		t.Errorf("DefaultNamespaces = %v, want all namespaces", opts.DefaultNamespaces)
+	}
+}
@@ -0,0 +1,85 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Command storage-migrator rewrites the stored MyResources in the storage
+// version of the CRD and prunes its status.storedVersions, once, outside of
+// the manager. Run it again to resume an interrupted migration.
+package main
+
+import (
+	"flag"
+	"os"
+
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	utilruntime "k8s.io/apimachinery/pkg/util/runtime"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log/zap"
+
+	"github.com/myid/myresource/internal/migration"
+)
+
+var (
+	scheme = runtime.NewScheme()
+	logger = ctrl.Log.WithName("storage-migrator")
+)
+
+func init() {
+	utilruntime.Must(clientgoscheme.AddToScheme(scheme))
+	utilruntime.Must(apiextensionsv1.AddToScheme(scheme))
+}
+
+func main() {
+	var crdName string
+	var checkpointNamespace string
+	var checkpointName string
+	var pageSize int64
+	flag.StringVar(&crdName, "crd", "myresources.mygroup.myid.dev", "The name of the CRD whose objects are migrated.")
+	flag.StringVar(&checkpointNamespace, "checkpoint-namespace", "myresource-kb-system",
+		"The namespace of the ConfigMap keeping the progress of the migration.")
+	flag.StringVar(&checkpointName, "checkpoint-name", "myresource-storage-migration",
+		"The name of the ConfigMap keeping the progress of the migration, shared with --enable-storage-migration "+
+			"of the manager.")
+	flag.Int64Var(&pageSize, "page-size", migration.DefaultPageSize,
+		"The number of objects migrated at once, the progress is saved after each page.")
+	opts := zap.Options{
+		Development: true,
+	}
+	opts.BindFlags(flag.CommandLine)
+	flag.Parse()
+	ctrl.SetLogger(zap.New(zap.UseFlagOptions(&opts)))
+
+	c, err := client.New(ctrl.GetConfigOrDie(), client.Options{Scheme: scheme})
+	if err != nil {
+		logger.Error(err, "unable to create client")
+		os.Exit(1)
+	}
+	m := &migration.Migrator{
+		Client:     c,
+		Reader:     c,
+		CRD:        crdName,
+		Checkpoint: types.NamespacedName{Namespace: checkpointNamespace, Name: checkpointName},
+		PageSize:   pageSize,
+	}
+	ctx := ctrl.LoggerInto(ctrl.SetupSignalHandler(), logger)
+	if err := m.Migrate(ctx); err != nil {
+		logger.Error(err, "storage migration failed, run again to resume it")
+		os.Exit(1)
+	}
+}
@@ -0,0 +1,207 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotator
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"math/big"
+	"time"
+)
+
+// _backdate tolerates clock skew between the manager and the API server.
+const _backdate = time.Hour
+
+// keyPairs are the PEM encoded CA and serving certificates with their keys,
+// and the CA replaced by the last renewal of the CA, if any.
+type keyPairs struct {
+	caCert, caKey  []byte
+	cert, key      []byte
+	previousCACert []byte
+}
+
+// caBundle returns the CAs the API server must trust, the current one first.
+func (k *keyPairs) caBundle() []byte {
+	if k.previousCACert == nil {
+		return k.caCert
+	}
+	return append(append([]byte{}, k.caCert...), k.previousCACert...)
+}
+
+// validAt checks that the serving certificate is signed by the CA, covers
+// dnsNames and, like the CA, is still valid at t.
+func (k *keyPairs) validAt(t time.Time, dnsNames []string) error {
+	if err := checkCA(k.caCert, k.caKey, t); err != nil {
+		return err
+	}
+	cert, err := parseCert(k.cert)
+	if err != nil {
+		return err
+	}
+	if _, err := parseKey(k.key); err != nil {
+		return err
+	}
+	roots := x509.NewCertPool()
+	roots.AppendCertsFromPEM(k.caCert)
+	for _, name := range dnsNames {
+		_, err := cert.Verify(x509.VerifyOptions{
+			DNSName:     name,
+			Roots:       roots,
+			CurrentTime: t,
+		})
+		if err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// checkCA checks that the CA certificate matches its key and is valid at t.
+func checkCA(certPEM, keyPEM []byte, t time.Time) error {
+	if err := checkCACert(certPEM, t); err != nil {
+		return err
+	}
+	cert, _ := parseCert(certPEM)
+	key, err := parseKey(keyPEM)
+	if err != nil {
+		return err
+	}
+	if !key.PublicKey.Equal(cert.PublicKey) {
+		return errors.New("CA key does not match the CA certificate")
+	}
+	return nil
+}
+
+// checkCACert checks that the CA certificate is valid at t.
+func checkCACert(certPEM []byte, t time.Time) error {
+	cert, err := parseCert(certPEM)
+	if err != nil {
+		return err
+	}
+	if !cert.IsCA || t.After(cert.NotAfter) {
+		return fmt.Errorf("CA certificate expires at %s", cert.NotAfter)
+	}
+	return nil
+}
+
+// newCA returns a self-signed CA certificate and its key.
+func newCA(now time.Time, validity time.Duration) ([]byte, []byte, error) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := newTemplate("myresource-webhook-ca", now, validity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageDigitalSignature
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encode(der, key)
+}
+
+// newServingCert returns a serving certificate for dnsNames signed by the CA.
+func newServingCert(caCertPEM, caKeyPEM []byte, dnsNames []string, now time.Time, validity time.Duration) ([]byte, []byte, error) {
+	if len(dnsNames) == 0 {
+		return nil, nil, errors.New("no DNS name for the serving certificate")
+	}
+	caCert, err := parseCert(caCertPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	caKey, err := parseKey(caKeyPEM)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := newTemplate(dnsNames[0], now, validity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.DNSNames = dnsNames
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth}
+	// the serving certificate must not outlive its CA
+	if template.NotAfter.After(caCert.NotAfter) {
+		template.NotAfter = caCert.NotAfter
+	}
+	der, err := x509.CreateCertificate(rand.Reader, template, caCert, &key.PublicKey, caKey)
+	if err != nil {
+		return nil, nil, err
+	}
+	return encode(der, key)
+}
+
+func newTemplate(commonName string, now time.Time, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject:      pkix.Name{CommonName: commonName},
+		NotBefore:    now.Add(-_backdate),
+		NotAfter:     now.Add(validity),
+	}, nil
+}
+
+func encode(der []byte, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	keyDER, err := x509.MarshalPKCS8PrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})
+	keyPEM := pem.EncodeToMemory(&pem.Block{Type: "PRIVATE KEY", Bytes: keyDER})
+	return certPEM, keyPEM, nil
+}
+
+func parseCert(data []byte) (*x509.Certificate, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "CERTIFICATE" {
+		return nil, errors.New("no PEM encoded certificate")
+	}
+	return x509.ParseCertificate(block.Bytes)
+}
+
+func parseKey(data []byte) (*ecdsa.PrivateKey, error) {
+	block, _ := pem.Decode(data)
+	if block == nil || block.Type != "PRIVATE KEY" {
+		return nil, errors.New("no PEM encoded private key")
+	}
+	key, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, err
+	}
+	ecKey, ok := key.(*ecdsa.PrivateKey)
+	if !ok {
+		return nil, fmt.Errorf("unsupported private key type %T", key)
+	}
+	return ecKey, nil
+}
@@ -0,0 +1,313 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certrotator provisions the serving certificate of the webhook server
+// without cert-manager. A self-signed CA and a serving certificate signed by it
+// are kept in a Secret shared by all replicas, the serving pair is written to
+// the certificate directory of the webhook server and the CA is injected into
+// the caBundle of the conversion webhook of the CRDs and of the webhook
+// configurations. Certificates are renewed before they expire.
+//
+// When the CA is renewed, the replaced CA stays in the Secret and in the
+// injected caBundle until it expires, so that the serving certificates it
+// signed are still trusted while the replicas pick up the new one, and the
+// caBundle holds the new CA before any replica serves a certificate signed
+// by it.
+package certrotator
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Keys of the Secret holding the certificates.
+const (
+	CACertKey = "ca.crt"
+	CAKeyKey  = "ca.key"
+	// CertKey and KeyKey are also the file names expected by the webhook server.
+	CertKey = corev1.TLSCertKey
+	KeyKey  = corev1.TLSPrivateKeyKey
+	// PreviousCACertKey is the CA replaced by the last renewal of the CA, kept
+	// in the caBundle until it expires.
+	PreviousCACertKey = "previous-ca.crt"
+)
+
+// Rotator keeps the webhook certificates valid. It implements manager.Runnable,
+// Ensure must be called once before the manager starts the webhook server.
+type Rotator struct {
+	// Client writes the Secret and patches the CRDs and webhook configurations.
+	Client client.Client
+	// Reader reads the same objects, it should not be backed by the cache so
+	// that no cluster-wide Secret informer is started.
+	Reader client.Reader
+	// Secret holds the CA and the serving certificate.
+	Secret types.NamespacedName
+	// CertDir is the directory the webhook server reads tls.crt and tls.key from.
+	CertDir string
+	// DNSNames are the names the serving certificate is valid for, e.g. the
+	// DNS names of the webhook Service.
+	DNSNames []string
+	// CRDs are the names of the CRDs whose conversion webhook trusts the CA.
+	CRDs []string
+	// MutatingWebhooks and ValidatingWebhooks are the names of the webhook
+	// configurations whose webhooks trust the CA.
+	MutatingWebhooks   []string
+	ValidatingWebhooks []string
+	// CAValidity and CertValidity are the lifetimes of new certificates.
+	CAValidity   time.Duration
+	CertValidity time.Duration
+	// RotateBefore is how long before expiry a certificate is renewed.
+	RotateBefore time.Duration
+	// CheckInterval is how often the certificates are checked.
+	CheckInterval time.Duration
+
+	// now is replaced in tests
+	now func() time.Time
+}
+
+// Start checks the certificates every CheckInterval until ctx is done.
+func (r *Rotator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("certrotator")
+
+	ticker := time.NewTicker(r.CheckInterval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+		if err := r.Ensure(ctx); err != nil {
+			logger.Error(err, "unable to rotate webhook certificates")
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica
+// serves webhooks and needs the certificates on its own disk.
+func (r *Rotator) NeedLeaderElection() bool {
+	return false
+}
+
+// Ensure renews the certificates in the Secret when they are missing or about
+// to expire, writes the serving pair to CertDir and injects the CA.
+func (r *Rotator) Ensure(ctx context.Context) error {
+	var certs *keyPairs
+	// replicas starting together race to create the Secret, the loser adopts
+	// the certificates of the winner
+	err := retry.OnError(retry.DefaultBackoff, func(err error) bool {
+		return errors.IsConflict(err) || errors.IsAlreadyExists(err)
+	}, func() error {
+		var err error
+		certs, err = r.ensureSecret(ctx)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+	// the new CA must be trusted before its serving certificate is served
+	if err := r.injectCABundle(ctx, certs.caBundle()); err != nil {
+		return err
+	}
+	return r.writeCertDir(certs)
+}
+
+// ensureSecret returns the certificates of the Secret, renewing them first if needed.
+func (r *Rotator) ensureSecret(ctx context.Context) (*keyPairs, error) {
+	logger := log.FromContext(ctx).WithName("certrotator")
+
+	secret := &corev1.Secret{}
+	err := r.Reader.Get(ctx, r.Secret, secret)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, err
+	}
+	exists := err == nil
+
+	current := &keyPairs{
+		caCert:         secret.Data[CACertKey],
+		caKey:          secret.Data[CAKeyKey],
+		cert:           secret.Data[CertKey],
+		key:            secret.Data[KeyKey],
+		previousCACert: secret.Data[PreviousCACertKey],
+	}
+	deadline := r.clock().Add(r.RotateBefore)
+	if current.validAt(deadline, r.DNSNames) == nil {
+		if current.previousCACert == nil || checkCACert(current.previousCACert, r.clock()) == nil {
+			return current, nil
+		}
+		// the certificates signed by the previous CA expired with it
+		logger.Info("removing the expired previous webhook CA", "secret", r.Secret)
+		renewed := *current
+		renewed.previousCACert = nil
+		return &renewed, r.writeSecret(ctx, secret, exists, &renewed)
+	}
+
+	renewed := &keyPairs{}
+	if checkCA(current.caCert, current.caKey, deadline) == nil {
+		// keep the CA, so the injected caBundle stays valid
+		renewed.caCert, renewed.caKey = current.caCert, current.caKey
+		renewed.previousCACert = current.previousCACert
+	} else {
+		logger.Info("generating webhook CA", "secret", r.Secret)
+		if renewed.caCert, renewed.caKey, err = newCA(r.clock(), r.CAValidity); err != nil {
+			return nil, err
+		}
+		if checkCA(current.caCert, current.caKey, r.clock()) == nil {
+			// the replicas still serve certificates signed by the current CA
+			// until they read the new ones
+			renewed.previousCACert = current.caCert
+		}
+	}
+	logger.Info("generating webhook serving certificate", "secret", r.Secret, "dnsNames", r.DNSNames)
+	renewed.cert, renewed.key, err = newServingCert(renewed.caCert, renewed.caKey, r.DNSNames, r.clock(), r.CertValidity)
+	if err != nil {
+		return nil, err
+	}
+	if err := r.writeSecret(ctx, secret, exists, renewed); err != nil {
+		return nil, err
+	}
+	return renewed, nil
+}
+
+// writeSecret creates or updates secret with certs.
+func (r *Rotator) writeSecret(ctx context.Context, secret *corev1.Secret, exists bool, certs *keyPairs) error {
+	secret.Name = r.Secret.Name
+	secret.Namespace = r.Secret.Namespace
+	secret.Type = corev1.SecretTypeTLS
+	secret.Data = map[string][]byte{
+		CACertKey: certs.caCert,
+		CAKeyKey:  certs.caKey,
+		CertKey:   certs.cert,
+		KeyKey:    certs.key,
+	}
+	if certs.previousCACert != nil {
+		secret.Data[PreviousCACertKey] = certs.previousCACert
+	}
+	if exists {
+		return r.Client.Update(ctx, secret)
+	}
+	return r.Client.Create(ctx, secret)
+}
+
+// writeCertDir writes the serving pair for the webhook server, which reloads
+// it on change. Unchanged files are left alone.
+func (r *Rotator) writeCertDir(certs *keyPairs) error {
+	if err := os.MkdirAll(r.CertDir, 0o700); err != nil {
+		return err
+	}
+	for name, data := range map[string][]byte{CertKey: certs.cert, KeyKey: certs.key} {
+		path := filepath.Join(r.CertDir, name)
+		if current, err := os.ReadFile(path); err == nil && bytes.Equal(current, data) {
+			continue
+		}
+		// rename, so the webhook server never reads a partial file
+		tmp := path + ".tmp"
+		if err := os.WriteFile(tmp, data, 0o600); err != nil {
+			return err
+		}
+		if err := os.Rename(tmp, path); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// injectCABundle sets caBundle wherever the API server calls the webhook server.
+func (r *Rotator) injectCABundle(ctx context.Context, caBundle []byte) error {
+	for _, name := range r.CRDs {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := r.Reader.Get(ctx, types.NamespacedName{Name: name}, crd); err != nil {
+			return fmt.Errorf("getting CRD %s: %w", name, err)
+		}
+		conversion := crd.Spec.Conversion
+		if conversion == nil || conversion.Webhook == nil || conversion.Webhook.ClientConfig == nil ||
+			bytes.Equal(conversion.Webhook.ClientConfig.CABundle, caBundle) {
+			continue
+		}
+		patch := client.MergeFrom(crd.DeepCopy())
+		conversion.Webhook.ClientConfig.CABundle = caBundle
+		if err := r.Client.Patch(ctx, crd, patch); err != nil {
+			return fmt.Errorf("patching CRD %s: %w", name, err)
+		}
+	}
+
+	for _, name := range r.MutatingWebhooks {
+		config := &admissionregistrationv1.MutatingWebhookConfiguration{}
+		if err := r.Reader.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+			return fmt.Errorf("getting MutatingWebhookConfiguration %s: %w", name, err)
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		changed := false
+		for i := range config.Webhooks {
+			changed = setCABundle(&config.Webhooks[i].ClientConfig, caBundle) || changed
+		}
+		if err := r.patchIf(ctx, changed, config, patch); err != nil {
+			return fmt.Errorf("patching MutatingWebhookConfiguration %s: %w", name, err)
+		}
+	}
+
+	for _, name := range r.ValidatingWebhooks {
+		config := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := r.Reader.Get(ctx, types.NamespacedName{Name: name}, config); err != nil {
+			return fmt.Errorf("getting ValidatingWebhookConfiguration %s: %w", name, err)
+		}
+		patch := client.MergeFrom(config.DeepCopy())
+		changed := false
+		for i := range config.Webhooks {
+			changed = setCABundle(&config.Webhooks[i].ClientConfig, caBundle) || changed
+		}
+		if err := r.patchIf(ctx, changed, config, patch); err != nil {
+			return fmt.Errorf("patching ValidatingWebhookConfiguration %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+func (r *Rotator) patchIf(ctx context.Context, changed bool, obj client.Object, patch client.Patch) error {
+	if !changed {
+		return nil
+	}
+	return r.Client.Patch(ctx, obj, patch)
+}
+
+// setCABundle reports whether the caBundle of config had to be changed.
+func setCABundle(config *admissionregistrationv1.WebhookClientConfig, caBundle []byte) bool {
+	if bytes.Equal(config.CABundle, caBundle) {
+		return false
+	}
+	config.CABundle = caBundle
+	return true
+}
+
+func (r *Rotator) clock() time.Time {
+	if r.now != nil {
+		return r.now()
+	}
+	return time.Now()
+}
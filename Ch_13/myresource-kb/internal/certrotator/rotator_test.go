@@ -0,0 +1,235 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certrotator
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	admissionregistrationv1 "k8s.io/api/admissionregistration/v1"
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_Rotator_Ensure(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := apiextensionsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	crd := &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: "myresources.mygroup.myid.dev"},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Conversion: &apiextensionsv1.CustomResourceConversion{
+				Strategy: apiextensionsv1.WebhookConverter,
+				Webhook: &apiextensionsv1.WebhookConversion{
+					ClientConfig: &apiextensionsv1.WebhookClientConfig{},
+				},
+			},
+		},
+	}
+	validating := &admissionregistrationv1.ValidatingWebhookConfiguration{
+		ObjectMeta: metav1.ObjectMeta{Name: "validating"},
+		Webhooks:   []admissionregistrationv1.ValidatingWebhook{{Name: "v.myid.dev"}},
+	}
+	c := fake.NewClientBuilder().WithScheme(scheme).WithObjects(crd, validating).Build()
+
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	r := &Rotator{
+		Client:             c,
+		Reader:             c,
+		Secret:             types.NamespacedName{Namespace: "system", Name: "webhook-server-cert"},
+		CertDir:            t.TempDir(),
+		DNSNames:           []string{"webhook-service.system.svc", "webhook-service.system.svc.cluster.local"},
+		CRDs:               []string{crd.Name},
+		ValidatingWebhooks: []string{validating.Name},
+		CAValidity:         365 * 24 * time.Hour,
+		CertValidity:       90 * 24 * time.Hour,
+		RotateBefore:       30 * 24 * time.Hour,
+		now:                func() time.Time { return now },
+	}
+	ctx := context.Background()
+
+	secretData := func() map[string][]byte {
+		secret := &corev1.Secret{}
+		if err := c.Get(ctx, r.Secret, secret); err != nil {
+			t.Fatal(err)
+		}
+		return secret.Data
+	}
+	caBundles := func() (crdBundle, webhookBundle []byte) {
+		gotCRD := &apiextensionsv1.CustomResourceDefinition{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(crd), gotCRD); err != nil {
+			t.Fatal(err)
+		}
+		gotValidating := &admissionregistrationv1.ValidatingWebhookConfiguration{}
+		if err := c.Get(ctx, client.ObjectKeyFromObject(validating), gotValidating); err != nil {
+			t.Fatal(err)
+		}
+		return gotCRD.Spec.Conversion.Webhook.ClientConfig.CABundle, gotValidating.Webhooks[0].ClientConfig.CABundle
+	}
+
+	// case 1: nothing yet, the certificates are generated and injected
+	if err := r.Ensure(ctx); err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	first := secretData()
+	pairs := &keyPairs{caCert: first[CACertKey], caKey: first[CAKeyKey], cert: first[CertKey], key: first[KeyKey]}
+	if err := pairs.validAt(now, r.DNSNames); err != nil {
+		t.Fatalf("Ensure() generated invalid certificates: %v", err)
+	}
+	written, err := os.ReadFile(filepath.Join(r.CertDir, CertKey))
+	if err != nil || !bytes.Equal(written, first[CertKey]) {
+		t.Errorf("Ensure() did not write %s to the cert dir: %v", CertKey, err)
+	}
+	if crdBundle, webhookBundle := caBundles(); !bytes.Equal(crdBundle, first[CACertKey]) || !bytes.Equal(webhookBundle, first[CACertKey]) {
+		t.Errorf("Ensure() did not inject the CA bundle")
+	}
+
+	// case 2: still valid, nothing changes
+	now = now.Add(30 * 24 * time.Hour)
+	if err := r.Ensure(ctx); err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	if second := secretData(); !bytes.Equal(second[CertKey], first[CertKey]) {
+		t.Errorf("Ensure() renewed a valid serving certificate")
+	}
+
+	// case 3: the serving certificate is about to expire, it is renewed with the same CA
+	now = now.Add(40 * 24 * time.Hour)
+	if err := r.Ensure(ctx); err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	third := secretData()
+	if bytes.Equal(third[CertKey], first[CertKey]) {
+		t.Errorf("Ensure() did not renew the expiring serving certificate")
+	}
+	if !bytes.Equal(third[CACertKey], first[CACertKey]) {
+		t.Errorf("Ensure() replaced a valid CA")
+	}
+
+	// case 4: the CA is about to expire, both are renewed and the new CA is
+	// injected next to the previous one
+	now = now.Add(270 * 24 * time.Hour)
+	if err := r.Ensure(ctx); err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	fourth := secretData()
+	if bytes.Equal(fourth[CACertKey], first[CACertKey]) {
+		t.Errorf("Ensure() did not renew the expiring CA")
+	}
+	if !bytes.Equal(fourth[PreviousCACertKey], first[CACertKey]) {
+		t.Errorf("Ensure() did not keep the previous CA")
+	}
+	wantBundle := append(append([]byte{}, fourth[CACertKey]...), first[CACertKey]...)
+	if crdBundle, webhookBundle := caBundles(); !bytes.Equal(crdBundle, wantBundle) || !bytes.Equal(webhookBundle, wantBundle) {
+		t.Errorf("Ensure() did not inject both CAs")
+	}
+
+	// case 5: the previous CA expired, it is removed from the bundle
+	now = now.Add(30 * 24 * time.Hour)
+	if err := r.Ensure(ctx); err != nil {
+		t.Fatalf("Ensure() error = %v", err)
+	}
+	fifth := secretData()
+	if _, found := fifth[PreviousCACertKey]; found {
+		t.Errorf("Ensure() kept the expired previous CA")
+	}
+	if !bytes.Equal(fifth[CertKey], fourth[CertKey]) || !bytes.Equal(fifth[CACertKey], fourth[CACertKey]) {
+		t.Errorf("Ensure() renewed valid certificates")
+	}
+	if crdBundle, webhookBundle := caBundles(); !bytes.Equal(crdBundle, fifth[CACertKey]) || !bytes.Equal(webhookBundle, fifth[CACertKey]) {
+		t.Errorf("Ensure() did not remove the previous CA from the bundle")
+	}
+}
+
+func Test_keyPairs_validAt(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	caCert, caKey, err := newCA(now, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	cert, key, err := newServingCert(caCert, caKey, []string{"svc.ns.svc"}, now, 48*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+	otherCA, otherKey, err := newCA(now, 24*time.Hour)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	tests := []struct {
+		name     string
+		pairs    keyPairs
+		at       time.Time
+		dnsNames []string
+		wantErr  bool
+	}{
+		{
+			name:     "case 1: valid",
+			pairs:    keyPairs{caCert: caCert, caKey: caKey, cert: cert, key: key},
+			at:       now,
+			dnsNames: []string{"svc.ns.svc"},
+		},
+		{
+			name:     "case 2: capped at the CA expiry",
+			pairs:    keyPairs{caCert: caCert, caKey: caKey, cert: cert, key: key},
+			at:       now.Add(25 * time.Hour),
+			dnsNames: []string{"svc.ns.svc"},
+			wantErr:  true,
+		},
+		{
+			name:     "case 3: other DNS name",
+			pairs:    keyPairs{caCert: caCert, caKey: caKey, cert: cert, key: key},
+			at:       now,
+			dnsNames: []string{"other.ns.svc"},
+			wantErr:  true,
+		},
+		{
+			name:     "case 4: other CA",
+			pairs:    keyPairs{caCert: otherCA, caKey: otherKey, cert: cert, key: key},
+			at:       now,
+			dnsNames: []string{"svc.ns.svc"},
+			wantErr:  true,
+		},
+		{
+			name:    "case 5: empty",
+			at:      now,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.pairs.validAt(tt.at, tt.dnsNames); (err != nil) != tt.wantErr {
+				t.Errorf("validAt() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
@@ -0,0 +1,276 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package config loads the versioned configuration file of the manager.
+package config
+
+import (
+	"errors"
+	"flag"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+
+	"go.uber.org/zap/zapcore"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/yaml"
+)
+
+const (
+	// APIVersion is the only supported version of the configuration file.
+	APIVersion = "config.myid.dev/v1alpha1"
+	// Kind is the kind of the configuration file.
+	Kind = "ControllerManagerConfig"
+
+	// MaxConcurrentReconcilesLimit is the highest accepted value of
+	// controller.maxConcurrentReconciles.
+	MaxConcurrentReconcilesLimit = 64
+)
+
+// ControllerManagerConfig is the configuration file of the manager. Every field
+// maps to a command line flag, and flags set on the command line take precedence.
+// LogLevel and the Controller settings can be changed without restarting the manager.
+type ControllerManagerConfig struct {
+	metav1.TypeMeta `json:",inline"`
+
+	// LogLevel is the zap log level: debug, info, error or any integer value > 0.
+	LogLevel string `json:"logLevel,omitempty"`
+
+	// Metrics configures the metrics endpoint.
+	Metrics MetricsConfig `json:"metrics,omitempty"`
+
+	// Health configures the health probe endpoint.
+	Health HealthConfig `json:"health,omitempty"`
+
+	// LeaderElection configures leader election.
+	LeaderElection LeaderElectionConfig `json:"leaderElection,omitempty"`
+
+	// Webhook configures the webhook server.
+	Webhook WebhookConfig `json:"webhook,omitempty"`
+
+	// EnableHTTP2 enables HTTP/2 for the metrics and webhook servers.
+	EnableHTTP2 *bool `json:"enableHTTP2,omitempty"`
+
+	// WatchNamespaces restricts the manager to these namespaces.
+	WatchNamespaces []string `json:"watchNamespaces,omitempty"`
+
+	// Sharding configures the sharded reconciliation.
+	Sharding ShardingConfig `json:"sharding,omitempty"`
+
+	// Controller configures the MyResource controller.
+	Controller ControllerConfig `json:"controller,omitempty"`
+
+	// Tracing configures the export of the reconcile traces.
+	Tracing TracingConfig `json:"tracing,omitempty"`
+}
+
+// MetricsConfig configures the metrics endpoint.
+type MetricsConfig struct {
+	// BindAddress is the address the metrics endpoint binds to, "0" disables it.
+	BindAddress string `json:"bindAddress,omitempty"`
+	// Secure serves the metrics endpoint via HTTPS.
+	Secure *bool `json:"secure,omitempty"`
+}
+
+// HealthConfig configures the health probe endpoint.
+type HealthConfig struct {
+	// BindAddress is the address the probe endpoint binds to.
+	BindAddress string `json:"bindAddress,omitempty"`
+}
+
+// LeaderElectionConfig configures leader election.
+type LeaderElectionConfig struct {
+	// Enabled ensures there is only one active controller manager.
+	Enabled *bool `json:"enabled,omitempty"`
+}
+
+// WebhookConfig configures the webhook server.
+type WebhookConfig struct {
+	// Enabled registers the conversion webhook.
+	Enabled *bool `json:"enabled,omitempty"`
+	// CertRotation generates and renews the webhook certificates without cert-manager.
+	CertRotation *bool `json:"certRotation,omitempty"`
+	// CertDir is the directory the webhook server reads its certificate from.
+	CertDir string `json:"certDir,omitempty"`
+	// Port is the port the webhook server listens on.
+	Port *int `json:"port,omitempty"`
+}
+
+// ShardingConfig configures the sharded reconciliation.
+type ShardingConfig struct {
+	// Enabled spreads the MyResources over all replicas of the manager.
+	Enabled *bool `json:"enabled,omitempty"`
+	// LeaseNamespace is the namespace holding the shard Leases.
+	LeaseNamespace string `json:"leaseNamespace,omitempty"`
+}
+
+// TracingConfig configures the export of the reconcile traces.
+type TracingConfig struct {
+	// Endpoint is the host:port of the OTLP gRPC collector, empty disables tracing.
+	Endpoint string `json:"endpoint,omitempty"`
+	// Insecure sends the traces to the collector without TLS.
+	Insecure *bool `json:"insecure,omitempty"`
+	// Stdout writes the traces to stdout instead of a collector.
+	Stdout *bool `json:"stdout,omitempty"`
+}
+
+// ControllerConfig configures the MyResource controller.
+type ControllerConfig struct {
+	// MaxConcurrentReconciles is the maximum number of MyResources reconciled at once.
+	MaxConcurrentReconciles *int `json:"maxConcurrentReconciles,omitempty"`
+	// RequeueInterval is how often a MyResource is reconciled again after a
+	// successful reconcile, 0 only reconciles on changes.
+	RequeueInterval *metav1.Duration `json:"requeueInterval,omitempty"`
+}
+
+// Load reads and validates the configuration file at path.
+func Load(path string) (*ControllerManagerConfig, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	cfg := &ControllerManagerConfig{}
+	if err := yaml.UnmarshalStrict(data, cfg); err != nil {
+		return nil, fmt.Errorf("decoding %s: %w", path, err)
+	}
+	if err := cfg.Validate(); err != nil {
+		return nil, fmt.Errorf("validating %s: %w", path, err)
+	}
+	return cfg, nil
+}
+
+// Validate checks the version and the values of the configuration.
+func (c *ControllerManagerConfig) Validate() error {
+	var errs []error
+	if c.APIVersion != APIVersion || c.Kind != Kind {
+		errs = append(errs, fmt.Errorf("unsupported configuration %s/%s, expected %s/%s",
+			c.APIVersion, c.Kind, APIVersion, Kind))
+	}
+	if c.LogLevel != "" {
+		if _, err := ParseLogLevel(c.LogLevel); err != nil {
+			errs = append(errs, err)
+		}
+	}
+	if n := c.Controller.MaxConcurrentReconciles; n != nil && (*n < 1 || *n > MaxConcurrentReconcilesLimit) {
+		errs = append(errs, fmt.Errorf("controller.maxConcurrentReconciles must be between 1 and %d, got %d",
+			MaxConcurrentReconcilesLimit, *n))
+	}
+	if c.Tracing.Endpoint != "" && c.Tracing.Stdout != nil && *c.Tracing.Stdout {
+		errs = append(errs, errors.New("tracing.endpoint and tracing.stdout cannot be both set"))
+	}
+	if d := c.Controller.RequeueInterval; d != nil && d.Duration < 0 {
+		errs = append(errs, fmt.Errorf("controller.requeueInterval must not be negative, got %s", d.Duration))
+	}
+	return errors.Join(errs...)
+}
+
+// ApplyTo sets the flags of fs from the configuration, except the flags that
+// were set on the command line. It must be called after fs has been parsed.
+func (c *ControllerManagerConfig) ApplyTo(fs *flag.FlagSet) error {
+	explicit := map[string]bool{}
+	fs.Visit(func(f *flag.Flag) {
+		explicit[f.Name] = true
+	})
+
+	for name, value := range c.flagValues() {
+		if explicit[name] {
+			continue
+		}
+		if fs.Lookup(name) == nil {
+			return fmt.Errorf("no flag %q for the configuration file", name)
+		}
+		if err := fs.Set(name, value); err != nil {
+			return fmt.Errorf("setting %q from the configuration file: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// flagValues returns the flag values of the fields set in the configuration.
+func (c *ControllerManagerConfig) flagValues() map[string]string {
+	values := map[string]string{}
+	setString := func(name, value string) {
+		if value != "" {
+			values[name] = value
+		}
+	}
+	setBool := func(name string, value *bool) {
+		if value != nil {
+			values[name] = strconv.FormatBool(*value)
+		}
+	}
+
+	setString("zap-log-level", c.LogLevel)
+	setString("metrics-bind-address", c.Metrics.BindAddress)
+	setBool("metrics-secure", c.Metrics.Secure)
+	setString("health-probe-bind-address", c.Health.BindAddress)
+	setBool("leader-elect", c.LeaderElection.Enabled)
+	setBool("enable-webhooks", c.Webhook.Enabled)
+	setBool("enable-cert-rotation", c.Webhook.CertRotation)
+	setString("webhook-cert-dir", c.Webhook.CertDir)
+	if p := c.Webhook.Port; p != nil {
+		values["webhook-port"] = strconv.Itoa(*p)
+	}
+	setBool("enable-http2", c.EnableHTTP2)
+	setString("watch-namespaces", strings.Join(c.WatchNamespaces, ","))
+	setBool("enable-sharding", c.Sharding.Enabled)
+	setString("shard-lease-namespace", c.Sharding.LeaseNamespace)
+	setString("otlp-endpoint", c.Tracing.Endpoint)
+	setBool("otlp-insecure", c.Tracing.Insecure)
+	setBool("trace-stdout", c.Tracing.Stdout)
+	if n := c.Controller.MaxConcurrentReconciles; n != nil {
+		values["max-concurrent-reconciles"] = strconv.Itoa(*n)
+	}
+	if d := c.Controller.RequeueInterval; d != nil {
+		values["requeue-interval"] = d.Duration.String()
+	}
+	return values
+}
+
+// ParseLogLevel parses a log level the same way as the --zap-log-level flag.
+func ParseLogLevel(value string) (zapcore.Level, error) {
+	switch strings.ToLower(value) {
+	case "debug":
+		return zapcore.DebugLevel, nil
+	case "info":
+		return zapcore.InfoLevel, nil
+	case "error":
+		return zapcore.ErrorLevel, nil
+	}
+	level, err := strconv.Atoi(value)
+	if err != nil || level <= 0 {
+		return 0, fmt.Errorf("invalid log level %q", value)
+	}
+	return zapcore.Level(int8(-level)), nil
+}
+
+// RequeueIntervalOrDefault returns the configured requeue interval, or def when unset.
+func (c *ControllerConfig) RequeueIntervalOrDefault(def time.Duration) time.Duration {
+	if c.RequeueInterval == nil {
+		return def
+	}
+	return c.RequeueInterval.Duration
+}
+
+// MaxConcurrentReconcilesOrDefault returns the configured concurrency, or def when unset.
+func (c *ControllerConfig) MaxConcurrentReconcilesOrDefault(def int) int {
+	if c.MaxConcurrentReconciles == nil {
+		return def
+	}
+	return *c.MaxConcurrentReconciles
+}
@@ -0,0 +1,158 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"flag"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"go.uber.org/zap/zapcore"
+	"k8s.io/utils/ptr"
+)
+
+func Test_Load(t *testing.T) {
+	tests := []struct {
+		name    string
+		content string
+		wantErr bool
+	}{
+		{
+			name: "case 1: valid",
+			content: `apiVersion: config.myid.dev/v1alpha1
+kind: ControllerManagerConfig
+logLevel: debug
+controller:
+  maxConcurrentReconciles: 4
+  requeueInterval: 5m
+`,
+		},
+		{
+			name: "case 2: wrong version",
+			content: `apiVersion: config.myid.dev/v1
+kind: ControllerManagerConfig
+`,
+			wantErr: true,
+		},
+		{
+			name: "case 3: unknown field",
+			content: `apiVersion: config.myid.dev/v1alpha1
+kind: ControllerManagerConfig
+logLevl: debug
+`,
+			wantErr: true,
+		},
+		{
+			name: "case 4: invalid values",
+			content: `apiVersion: config.myid.dev/v1alpha1
+kind: ControllerManagerConfig
+logLevel: verbose
+controller:
+  maxConcurrentReconciles: 0
+  requeueInterval: -1m
+`,
+			wantErr: true,
+		},
+		{
+			name: "case 5: tracing to a collector and stdout",
+			content: `apiVersion: config.myid.dev/v1alpha1
+kind: ControllerManagerConfig
+tracing:
+  endpoint: otel-collector:4317
+  stdout: true
+`,
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			path := filepath.Join(t.TempDir(), "config.yaml")
+			if err := os.WriteFile(path, []byte(tt.content), 0o600); err != nil {
+				t.Fatal(err)
+			}
+			if _, err := Load(path); (err != nil) != tt.wantErr {
+				t.Errorf("Load() error = %v, wantErr %v", err, tt.wantErr)
+			}
+		})
+	}
+}
+
+func Test_ApplyTo(t *testing.T) {
+	fs := flag.NewFlagSet("test", flag.ContinueOnError)
+	metricsAddr := fs.String("metrics-bind-address", "0", "")
+	leaderElect := fs.Bool("leader-elect", false, "")
+	namespaces := fs.String("watch-namespaces", "", "")
+	maxConcurrent := fs.Int("max-concurrent-reconciles", 1, "")
+	interval := fs.Duration("requeue-interval", 0, "")
+	if err := fs.Parse([]string{"--max-concurrent-reconciles=2"}); err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &ControllerManagerConfig{
+		Metrics:         MetricsConfig{BindAddress: ":8443"},
+		LeaderElection:  LeaderElectionConfig{Enabled: ptr.To(true)},
+		WatchNamespaces: []string{"team-a", "team-b"},
+		Controller: ControllerConfig{
+			MaxConcurrentReconciles: ptr.To(8),
+		},
+	}
+	if err := cfg.ApplyTo(fs); err != nil {
+		t.Fatalf("ApplyTo() error = %v", err)
+	}
+
+	if *metricsAddr != ":8443" {
+		t.Errorf("metrics-bind-address = %q, want %q", *metricsAddr, ":8443")
+	}
+	if !*leaderElect {
+		t.Errorf("leader-elect = false, want true")
+	}
+	if *namespaces != "team-a,team-b" {
+		t.Errorf("watch-namespaces = %q, want %q", *namespaces, "team-a,team-b")
+	}
+	if *maxConcurrent != 2 {
+		t.Errorf("max-concurrent-reconciles = %d, want the command line value 2", *maxConcurrent)
+	}
+	if *interval != 0 {
+		t.Errorf("requeue-interval = %s, want the default 0s", *interval)
+	}
+}
+
+func Test_ParseLogLevel(t *testing.T) {
+	tests := []struct {
+		name    string
+		value   string
+		want    zapcore.Level
+		wantErr bool
+	}{
+		{name: "case 1: named", value: "Error", want: zapcore.ErrorLevel},
+		{name: "case 2: verbosity", value: "3", want: zapcore.Level(-3)},
+		{name: "case 3: zero", value: "0", wantErr: true},
+		{name: "case 4: unknown", value: "trace", wantErr: true},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := ParseLogLevel(tt.value)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("ParseLogLevel() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got != tt.want {
+				t.Errorf("ParseLogLevel() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"context"
+	"os"
+	"reflect"
+	"time"
+
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Watcher reloads the configuration file when its content changes. Only the
+// reloadable settings are handed to OnChange, changes to the other fields are
+// logged and take effect after a restart.
+type Watcher struct {
+	// Path is the configuration file.
+	Path string
+	// Interval is how often the file is read.
+	Interval time.Duration
+	// Current is the configuration the manager was started with.
+	Current *ControllerManagerConfig
+	// OnChange is called with every valid new configuration.
+	OnChange func(ctx context.Context, cfg *ControllerManagerConfig)
+
+	content []byte
+}
+
+// Start polls the configuration file until ctx is cancelled.
+func (w *Watcher) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("config")
+
+	w.content, _ = os.ReadFile(w.Path)
+	ticker := time.NewTicker(w.Interval)
+	defer ticker.Stop()
+	for {
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+
+		content, err := os.ReadFile(w.Path)
+		if err != nil {
+			logger.Error(err, "unable to read configuration file", "path", w.Path)
+			continue
+		}
+		if bytes.Equal(content, w.content) {
+			continue
+		}
+		w.content = content
+
+		cfg, err := Load(w.Path)
+		if err != nil {
+			logger.Error(err, "ignoring invalid configuration file, keeping the previous configuration")
+			continue
+		}
+		if w.Current != nil && !reflect.DeepEqual(restartFields(cfg), restartFields(w.Current)) {
+			logger.Info("configuration changes other than logLevel and controller need a restart of the manager")
+		}
+		w.Current = cfg
+		logger.Info("configuration file reloaded", "path", w.Path)
+		if w.OnChange != nil {
+			w.OnChange(ctx, cfg)
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, every replica
+// reloads its own configuration.
+func (w *Watcher) NeedLeaderElection() bool {
+	return false
+}
+
+// restartFields returns cfg without the fields that are reloaded at runtime.
+func restartFields(cfg *ControllerManagerConfig) ControllerManagerConfig {
+	c := *cfg
+	c.LogLevel = ""
+	c.Controller = ControllerConfig{}
+	return c
+}
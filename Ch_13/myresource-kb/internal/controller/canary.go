@@ -0,0 +1,255 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	_defaultCanaryTimeout = 10 * time.Minute
+
+	_canaryStartedReason    = "CanaryStarted"
+	_canaryPromotedReason   = "CanaryPromoted"
+	_canaryRolledBackReason = "CanaryRolledBack"
+
+	// _canaryDeletionRequeue is how often a rollout waits for the canary of the
+	// previous one to be deleted.
+	_canaryDeletionRequeue = 5 * time.Second
+)
+
+// reconcileCanary drives the canary rollout of myres and returns the image the
+// primary Deployment must run, along with how long to wait before checking the
+// canary again. The progress is recorded in myres.Status.Canary, each change
+// of phase is persisted before its event is emitted and before the canary
+// Deployment is created or deleted, so that a reconcile failing afterwards
+// does not replay it.
+func (a *MyResourceReconciler) reconcileCanary(
+	ctx context.Context,
+	myres *mygroupv1alpha1.MyResource,
+	ownerref *metav1.OwnerReference,
+) (string, time.Duration, error) {
+	logger := log.FromContext(ctx)
+	canary := createCanaryDeployment(myres, ownerref)
+
+	if myres.Spec.Canary == nil || myres.Spec.Suspend {
+		// a suspended MyResource serves no traffic, so there is nothing to protect
+		return myres.Spec.Image, 0, a.cancelCanary(ctx, myres, canary)
+	}
+
+	primary := appsv1.Deployment{}
+	err := a.Client.Get(ctx, types.NamespacedName{
+		Namespace: myres.GetNamespace(),
+		Name:      myres.GetName() + "-deployment",
+	}, &primary)
+	if errors.IsNotFound(err) {
+		// nothing is running yet, there is no previous image to protect
+		return myres.Spec.Image, 0, a.cancelCanary(ctx, myres, canary)
+	}
+	if err != nil {
+		return "", 0, err
+	}
+
+	var stable string
+	if containers := primary.Spec.Template.Spec.Containers; len(containers) > 0 {
+		stable = containers[0].Image
+	}
+	if stable == myres.Spec.Image {
+		return stable, 0, a.cancelCanary(ctx, myres, canary)
+	}
+
+	status := myres.Status.Canary
+	if status != nil && status.Image == myres.Spec.Image {
+		switch status.Phase {
+		case mygroupv1alpha1.CanaryPhasePromoted:
+			// the canary may be left over when the reconcile that promoted
+			// it failed afterwards
+			return myres.Spec.Image, 0, a.deleteCanary(ctx, canary)
+		case mygroupv1alpha1.CanaryPhaseRolledBack:
+			// keep the previous image until spec.image changes again
+			return stable, 0, a.deleteCanary(ctx, canary)
+		}
+	}
+
+	previous := appsv1.Deployment{}
+	err = a.Client.Get(ctx, client.ObjectKeyFromObject(canary), &previous)
+	if err != nil && !errors.IsNotFound(err) {
+		return "", 0, err
+	}
+	if err == nil && previous.DeletionTimestamp != nil {
+		// the canary of the previous rollout is still going away, applying now
+		// would be lost with it
+		return "", 0, requeueAfterError(
+			fmt.Errorf("canary deployment %s of the previous rollout is being deleted", canary.GetName()),
+			_canaryDeletionRequeue)
+	}
+
+	now := metav1.Now()
+	if status == nil || status.Image != myres.Spec.Image || status.Phase != mygroupv1alpha1.CanaryPhaseBaking {
+		status = &mygroupv1alpha1.CanaryStatus{
+			Image:     myres.Spec.Image,
+			Phase:     mygroupv1alpha1.CanaryPhaseBaking,
+			StartTime: now,
+			Message:   fmt.Sprintf("canary deployment %q started with image %q", canary.GetName(), myres.Spec.Image),
+		}
+		myres.Status.Canary = status
+		if err := a.updateStatus(ctx, myres); err != nil {
+			return "", 0, err
+		}
+		a.Recorder.Event(myres, corev1.EventTypeNormal, _canaryStartedReason, status.Message)
+	}
+
+	// the canary follows the drift policy of the primary Deployment, only the
+	// drift of the primary one is reported in the Drifted condition
+	_, err = a.applyWithPolicy(ctx, myres.Spec.DriftPolicy, canary)
+	if err != nil {
+		return "", 0, err
+	}
+
+	requeueAfter := evaluateCanary(myres.Spec.Canary, status, canary, now.Time)
+	logger.Info("evaluated canary", "image", status.Image, "phase", status.Phase)
+	switch status.Phase {
+	case mygroupv1alpha1.CanaryPhasePromoted:
+		if err := a.updateStatus(ctx, myres); err != nil {
+			return "", 0, err
+		}
+		a.Recorder.Event(myres, corev1.EventTypeNormal, _canaryPromotedReason, status.Message)
+		return myres.Spec.Image, 0, a.deleteCanary(ctx, canary)
+	case mygroupv1alpha1.CanaryPhaseRolledBack:
+		if err := a.updateStatus(ctx, myres); err != nil {
+			return "", 0, err
+		}
+		a.Recorder.Event(myres, corev1.EventTypeWarning, _canaryRolledBackReason, status.Message)
+		return stable, 0, a.deleteCanary(ctx, canary)
+	}
+	return stable, requeueAfter, nil
+}
+
+// evaluateCanary advances status according to the readiness of the canary
+// Deployment and returns how long to wait before the next evaluation.
+func evaluateCanary(
+	spec *mygroupv1alpha1.CanarySpec,
+	status *mygroupv1alpha1.CanaryStatus,
+	canary *appsv1.Deployment,
+	now time.Time,
+) time.Duration {
+	replicas := spec.Replicas
+	if replicas == 0 {
+		replicas = 1
+	}
+	if canary.Spec.Replicas != nil {
+		// the live count, which another field manager may own under the
+		// Preserve drift policy
+		replicas = *canary.Spec.Replicas
+	}
+	timeout := spec.Timeout.Duration
+	if timeout == 0 {
+		timeout = _defaultCanaryTimeout
+	}
+
+	ready := canary.Status.ObservedGeneration >= canary.GetGeneration() &&
+		canary.Status.UpdatedReplicas >= replicas &&
+		canary.Status.ReadyReplicas >= replicas
+	if ready {
+		if status.ReadyTime == nil {
+			readyTime := metav1.NewTime(now)
+			status.ReadyTime = &readyTime
+		}
+		baked := now.Sub(status.ReadyTime.Time)
+		if baked >= spec.BakeTime.Duration {
+			status.Phase = mygroupv1alpha1.CanaryPhasePromoted
+			status.Message = fmt.Sprintf("image %q stayed ready for %s and has been promoted",
+				status.Image, spec.BakeTime.Duration)
+			return 0
+		}
+		status.Message = fmt.Sprintf("canary is ready, baking for another %s", spec.BakeTime.Duration-baked)
+		return spec.BakeTime.Duration - baked
+	}
+
+	// readiness was lost, the bake time starts over once the canary is ready again
+	status.ReadyTime = nil
+	waited := now.Sub(status.StartTime.Time)
+	progressing := progressingCondition(canary, 0)
+	if waited >= timeout || (progressing != nil && progressing.Reason == _progressDeadlineExceededReason) {
+		status.Phase = mygroupv1alpha1.CanaryPhaseRolledBack
+		status.Message = fmt.Sprintf("image %q did not become ready within %s and has been rolled back",
+			status.Image, timeout)
+		return 0
+	}
+	status.Message = fmt.Sprintf("waiting for %d/%d canary replicas to be ready",
+		canary.Status.ReadyReplicas, replicas)
+	return timeout - waited
+}
+
+// cancelCanary removes a canary Deployment left over from a rollout that is no
+// longer wanted, e.g. because canaries were disabled or spec.image was reverted,
+// or from a rollout that ended.
+func (a *MyResourceReconciler) cancelCanary(
+	ctx context.Context,
+	myres *mygroupv1alpha1.MyResource,
+	canary *appsv1.Deployment,
+) error {
+	status := myres.Status.Canary
+	if status != nil && status.Phase == mygroupv1alpha1.CanaryPhaseBaking {
+		status.Phase = mygroupv1alpha1.CanaryPhaseRolledBack
+		status.ReadyTime = nil
+		status.Message = fmt.Sprintf("canary of image %q has been canceled", status.Image)
+		if err := a.updateStatus(ctx, myres); err != nil {
+			return err
+		}
+		a.Recorder.Event(myres, corev1.EventTypeNormal, _canaryRolledBackReason, status.Message)
+	}
+	return a.deleteCanary(ctx, canary)
+}
+
+// deleteCanary deletes the canary Deployment, when it exists and is not being
+// deleted already. It is read from the cache first, MyResources without a
+// canary cost no request.
+func (a *MyResourceReconciler) deleteCanary(
+	ctx context.Context,
+	canary *appsv1.Deployment,
+) error {
+	existing := appsv1.Deployment{}
+	err := a.Client.Get(ctx, client.ObjectKeyFromObject(canary), &existing)
+	if errors.IsNotFound(err) || err == nil && existing.DeletionTimestamp != nil {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+	return client.IgnoreNotFound(a.Client.Delete(ctx, &existing))
+}
+
+func createCanaryDeployment(
+	myres *mygroupv1alpha1.MyResource,
+	ownerref *metav1.OwnerReference,
+) *appsv1.Deployment {
+	deploy := createDeployment(myres, ownerref, myres.Spec.Image)
+	// the canary gets its own labels so that the selectors of both
+	// Deployments never overlap and computeStatus only sees the primary
+	labels := map[string]string{
+		"myresource-canary": myres.GetName(),
+	}
+	deploy.SetLabels(labels)
+	deploy.Spec.Selector = &metav1.LabelSelector{
+		MatchLabels: labels,
+	}
+	deploy.Spec.Template.SetLabels(labels)
+	replicas := int32(1)
+	if myres.Spec.Canary != nil && myres.Spec.Canary.Replicas > 0 {
+		replicas = myres.Spec.Canary.Replicas
+	}
+	deploy.Spec.Replicas = ptr.To(replicas)
+	deploy.SetName(myres.GetName() + "-canary")
+	return deploy
+}
@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"testing"
+	"time"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_evaluateCanary(t *testing.T) {
+	start := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	spec := &mygroupv1alpha1.CanarySpec{
+		Replicas: 2,
+		BakeTime: metav1.Duration{Duration: 5 * time.Minute},
+		Timeout:  metav1.Duration{Duration: 10 * time.Minute},
+	}
+	readyCanary := appsv1.DeploymentStatus{
+		UpdatedReplicas: 2,
+		ReadyReplicas:   2,
+	}
+
+	tests := []struct {
+		name          string
+		readyTime     *time.Time
+		canary        appsv1.DeploymentStatus
+		now           time.Time
+		wantPhase     mygroupv1alpha1.CanaryPhase
+		wantRequeue   time.Duration
+		wantReadyTime bool
+	}{
+		{
+			name:        "case 1: canary not ready yet",
+			canary:      appsv1.DeploymentStatus{UpdatedReplicas: 2, ReadyReplicas: 1},
+			now:         start.Add(time.Minute),
+			wantPhase:   mygroupv1alpha1.CanaryPhaseBaking,
+			wantRequeue: 9 * time.Minute,
+		},
+		{
+			name:          "case 2: canary became ready, bake starts",
+			canary:        readyCanary,
+			now:           start.Add(2 * time.Minute),
+			wantPhase:     mygroupv1alpha1.CanaryPhaseBaking,
+			wantRequeue:   5 * time.Minute,
+			wantReadyTime: true,
+		},
+		{
+			name:          "case 3: canary baked long enough",
+			readyTime:     &start,
+			canary:        readyCanary,
+			now:           start.Add(5 * time.Minute),
+			wantPhase:     mygroupv1alpha1.CanaryPhasePromoted,
+			wantReadyTime: true,
+		},
+		{
+			name:      "case 4: canary lost readiness and timed out",
+			readyTime: &start,
+			canary:    appsv1.DeploymentStatus{UpdatedReplicas: 2, ReadyReplicas: 0},
+			now:       start.Add(10 * time.Minute),
+			wantPhase: mygroupv1alpha1.CanaryPhaseRolledBack,
+		},
+		{
+			name: "case 5: canary exceeded its progress deadline",
+			canary: appsv1.DeploymentStatus{
+				Conditions: []appsv1.DeploymentCondition{
+					{
+						Type:   appsv1.DeploymentProgressing,
+						Status: corev1.ConditionFalse,
+						Reason: _progressDeadlineExceededReason,
+					},
+				},
+			},
+			now:       start.Add(time.Minute),
+			wantPhase: mygroupv1alpha1.CanaryPhaseRolledBack,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &mygroupv1alpha1.CanaryStatus{
+				Image:     "nginx:1.27",
+				Phase:     mygroupv1alpha1.CanaryPhaseBaking,
+				StartTime: metav1.NewTime(start),
+			}
+			if tt.readyTime != nil {
+				readyTime := metav1.NewTime(*tt.readyTime)
+				status.ReadyTime = &readyTime
+			}
+			canary := &appsv1.Deployment{Status: tt.canary}
+
+			gotRequeue := evaluateCanary(spec, status, canary, tt.now)
+			if gotRequeue != tt.wantRequeue {
+				t.Errorf("evaluateCanary() requeue = %v, want %v", gotRequeue, tt.wantRequeue)
+			}
+			if status.Phase != tt.wantPhase {
+				t.Errorf("evaluateCanary() phase = %v, want %v", status.Phase, tt.wantPhase)
+			}
+			if (status.ReadyTime != nil) != tt.wantReadyTime {
+				t.Errorf("evaluateCanary() readyTime = %v, want set %v", status.ReadyTime, tt.wantReadyTime)
+			}
+		})
+	}
+}
@@ -2,37 +2,99 @@ package controller
 
 import (
 	"context"
+	"errors"
+	"fmt"
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
 	corev1 "k8s.io/api/core/v1"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 )
 
+const _fieldOwner = "MyResourceReconciler"
+
+// validateSpec rejects the specs no Deployment can be built from, which
+// neither the CRD schema nor a validating webhook checks.
+func validateSpec(myres *mygroupv1alpha1.MyResource) error {
+	if myres.Spec.Image == "" {
+		return permanentError(errors.New("spec.image must not be empty"))
+	}
+	if myres.Spec.Memory.Sign() < 0 {
+		return permanentError(fmt.Errorf("spec.memory must not be negative, got %s", myres.Spec.Memory.String()))
+	}
+	return nil
+}
+
+// applyDeployment server-side applies the Deployment owned by myres, running image.
+// Unless the drift policy is Enforce, the live Deployment is first compared with
+// the desired one and the resulting drift report is returned. Under Preserve,
+// the fields owned by other field managers are left out of the apply.
 func (a *MyResourceReconciler) applyDeployment(
 	ctx context.Context,
 	myres *mygroupv1alpha1.MyResource,
 	ownerref *metav1.OwnerReference,
+	image string,
+) (*driftReport, error) {
+	return a.applyWithPolicy(ctx, myres.Spec.DriftPolicy, createDeployment(myres, ownerref, image))
+}
+
+// applyWithPolicy server-side applies deploy according to the drift policy, see
+// applyDeployment. deploy is updated with the applied Deployment.
+func (a *MyResourceReconciler) applyWithPolicy(
+	ctx context.Context,
+	policy mygroupv1alpha1.DriftPolicy,
+	deploy *appsv1.Deployment,
+) (*driftReport, error) {
+	if policy == "" || policy == mygroupv1alpha1.DriftPolicyEnforce {
+		return nil, a.patchDeployment(ctx, deploy, true)
+	}
+
+	force := policy != mygroupv1alpha1.DriftPolicyPreserve
+	report, err := a.detectDrift(ctx, deploy, force)
+	if err != nil {
+		return nil, err
+	}
+	if report.conflict != "" {
+		// refuse to take ownership of fields managed by someone else, but
+		// still roll out the other desired changes
+		preserved, err := withoutFields(deploy, report.conflictFields)
+		if err != nil || len(report.conflictFields) == 0 {
+			return report, err
+		}
+		err = a.Client.Patch(ctx, preserved, client.Apply, client.FieldOwner(_fieldOwner))
+		if err != nil {
+			return report, err
+		}
+		return report, runtime.DefaultUnstructuredConverter.FromUnstructured(preserved.Object, deploy)
+	}
+	return report, a.patchDeployment(ctx, deploy, force)
+}
+
+func (a *MyResourceReconciler) patchDeployment(
+	ctx context.Context,
+	deploy *appsv1.Deployment,
+	force bool,
 ) error {
-	deploy := createDeployment(myres, ownerref)
-	err := a.Client.Patch(
+	opts := []client.PatchOption{
+		client.FieldOwner(_fieldOwner),
+	}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	return a.Client.Patch(
 		ctx,
 		deploy,
 		client.Apply,
-		client.FieldOwner("MyResourceReconciler"),
-		client.ForceOwnership,
+		opts...,
 	)
-	//generation := deploy.GetGeneration()
-	//if generation == 1 {
-	//	a.EventRecorder.Eventf(myres, corev1.EventTypeNormal, "DeploymentCreated", "The deployment %q has been created", deploy.GetName())
-	//}
-	return err
 }
 
 func createDeployment(
 	myres *mygroupv1alpha1.MyResource,
 	ownerref *metav1.OwnerReference,
+	image string,
 ) *appsv1.Deployment {
 	deploy := &appsv1.Deployment{
 		ObjectMeta: metav1.ObjectMeta{
@@ -56,7 +118,7 @@ func createDeployment(
 					Containers: []corev1.Container{
 						{
 							Name:  "main",
-							Image: myres.Spec.Image,
+							Image: image,
 							Resources: corev1.ResourceRequirements{
 								Requests: corev1.ResourceList{
 									corev1.ResourceMemory: myres.Spec.Memory,
@@ -68,6 +130,16 @@ func createDeployment(
 			},
 		},
 	}
+	if myres.Spec.Strategy != nil {
+		deploy.Spec.Strategy = *myres.Spec.Strategy
+	}
+	replicas := int32(1)
+	if myres.Spec.Suspend {
+		replicas = 0
+	}
+	deploy.Spec.Replicas = &replicas
+	deploy.Spec.ProgressDeadlineSeconds = myres.Spec.ProgressDeadlineSeconds
+	deploy.Spec.MinReadySeconds = myres.Spec.MinReadySeconds
 	deploy.SetName(myres.GetName() + "-deployment")
 	deploy.SetNamespace(myres.GetNamespace())
 	deploy.SetGroupVersionKind(
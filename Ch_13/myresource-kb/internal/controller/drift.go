@@ -0,0 +1,188 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	_driftedCondition = "Drifted"
+
+	_inSyncReason            = "InSync"
+	_fieldsDriftedReason     = "FieldsDrifted"
+	_ownershipConflictReason = "OwnershipConflict"
+)
+
+// driftReport is the outcome of comparing the desired Deployment with the live one.
+type driftReport struct {
+	// fields lists the paths the apply would change on the live Deployment.
+	fields []string
+	// conflict holds the apiserver message when the apply was refused because
+	// fields are owned by other field managers.
+	conflict string
+	// conflictFields are the paths of these fields, as reported by the apiserver,
+	// e.g. .spec.template.spec.containers[name="main"].image.
+	conflictFields []string
+}
+
+// detectDrift server-side applies the desired Deployment in dry-run mode and
+// compares the result with the live object. When force is false, fields owned
+// by other managers are not taken over and a conflict is reported instead.
+func (a *MyResourceReconciler) detectDrift(
+	ctx context.Context,
+	desired *appsv1.Deployment,
+	force bool,
+) (*driftReport, error) {
+	live := appsv1.Deployment{}
+	err := a.Client.Get(ctx, client.ObjectKeyFromObject(desired), &live)
+	if errors.IsNotFound(err) {
+		return &driftReport{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	dryRun := desired.DeepCopy()
+	opts := []client.PatchOption{
+		client.FieldOwner(_fieldOwner),
+		client.DryRunAll,
+	}
+	if force {
+		opts = append(opts, client.ForceOwnership)
+	}
+	err = a.Client.Patch(ctx, dryRun, client.Apply, opts...)
+	if errors.IsConflict(err) {
+		return &driftReport{conflict: err.Error(), conflictFields: conflictFields(err)}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	fields, err := diffDeployments(&live, dryRun)
+	if err != nil {
+		return nil, err
+	}
+	return &driftReport{fields: fields}, nil
+}
+
+// conflictFields returns the paths of the fields of an apply conflict.
+func conflictFields(err error) []string {
+	var fields []string
+	if status, ok := err.(errors.APIStatus); ok && status.Status().Details != nil {
+		for _, cause := range status.Status().Details.Causes {
+			if cause.Type == metav1.CauseTypeFieldManagerConflict {
+				fields = append(fields, cause.Field)
+			}
+		}
+	}
+	return fields
+}
+
+// reportDrift records the drift report as a condition and emits an event when
+// the live Deployment does not match the desired state. A nil report means drift
+// detection is disabled and removes the condition.
+func (a *MyResourceReconciler) reportDrift(
+	myres *mygroupv1alpha1.MyResource,
+	report *driftReport,
+) {
+	if report == nil {
+		meta.RemoveStatusCondition(&myres.Status.Conditions, _driftedCondition)
+		return
+	}
+
+	condition := metav1.Condition{
+		Type:               _driftedCondition,
+		ObservedGeneration: myres.GetGeneration(),
+	}
+	switch {
+	case report.conflict != "":
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = _ownershipConflictReason
+		condition.Message = report.conflict
+		if len(report.conflictFields) > 0 {
+			condition.Message = fmt.Sprintf("fields preserved, owned by other managers: %s",
+				strings.Join(report.conflictFields, ", "))
+		}
+		a.Recorder.Event(myres, corev1.EventTypeWarning, _ownershipConflictReason, condition.Message)
+	case len(report.fields) > 0:
+		condition.Status = metav1.ConditionTrue
+		condition.Reason = _fieldsDriftedReason
+		condition.Message = fmt.Sprintf("drifted fields: %s", strings.Join(report.fields, ", "))
+		a.Recorder.Event(myres, corev1.EventTypeWarning, _fieldsDriftedReason, condition.Message)
+	default:
+		condition.Status = metav1.ConditionFalse
+		condition.Reason = _inSyncReason
+		condition.Message = "deployment matches the desired state"
+	}
+	meta.SetStatusCondition(&myres.Status.Conditions, condition)
+}
+
+// diffDeployments returns the sorted paths of the labels and spec fields that
+// differ between the live and the desired Deployment.
+func diffDeployments(live, desired *appsv1.Deployment) ([]string, error) {
+	liveSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&live.Spec)
+	if err != nil {
+		return nil, err
+	}
+	desiredSpec, err := runtime.DefaultUnstructuredConverter.ToUnstructured(&desired.Spec)
+	if err != nil {
+		return nil, err
+	}
+
+	fields := diffFields("spec", liveSpec, desiredSpec)
+	for key, value := range desired.GetLabels() {
+		if live.GetLabels()[key] != value {
+			fields = append(fields, fmt.Sprintf("metadata.labels.%s", key))
+		}
+	}
+	sort.Strings(fields)
+	return fields, nil
+}
+
+// diffFields walks two unstructured values and returns the paths where they differ.
+func diffFields(path string, live, desired interface{}) []string {
+	switch d := desired.(type) {
+	case map[string]interface{}:
+		l, ok := live.(map[string]interface{})
+		if !ok {
+			return []string{path}
+		}
+		var fields []string
+		for key, value := range d {
+			fields = append(fields, diffFields(path+"."+key, l[key], value)...)
+		}
+		for key := range l {
+			if _, found := d[key]; !found {
+				fields = append(fields, path+"."+key)
+			}
+		}
+		return fields
+	case []interface{}:
+		l, ok := live.([]interface{})
+		if !ok || len(l) != len(d) {
+			return []string{path}
+		}
+		var fields []string
+		for i := range d {
+			fields = append(fields, diffFields(fmt.Sprintf("%s[%d]", path, i), l[i], d[i])...)
+		}
+		return fields
+	default:
+		if !reflect.DeepEqual(live, desired) {
+			return []string{path}
+		}
+		return nil
+	}
+}
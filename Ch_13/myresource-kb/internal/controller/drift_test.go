@@ -0,0 +1,75 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/utils/ptr"
+)
+
+func Test_diffDeployments(t *testing.T) {
+	myres := &mygroupv1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      "myres",
+			Namespace: "default",
+		},
+		Spec: mygroupv1alpha1.MyResourceSpec{
+			Image:  "nginx",
+			Memory: resource.MustParse("256Mi"),
+		},
+	}
+	ownerRef := metav1.NewControllerRef(myres, mygroupv1alpha1.GroupVersion.WithKind("MyResource"))
+	desired := createDeployment(myres, ownerRef, myres.Spec.Image)
+
+	tests := []struct {
+		name   string
+		mutate func(live *appsv1.Deployment)
+		want   []string
+	}{
+		{
+			name:   "case 1: no drift",
+			mutate: func(live *appsv1.Deployment) {},
+			want:   nil,
+		},
+		{
+			name: "case 2: image changed",
+			mutate: func(live *appsv1.Deployment) {
+				live.Spec.Template.Spec.Containers[0].Image = "httpd"
+			},
+			want: []string{"spec.template.spec.containers[0].image"},
+		},
+		{
+			name: "case 3: replicas added and label removed",
+			mutate: func(live *appsv1.Deployment) {
+				live.Spec.Replicas = ptr.To[int32](3)
+				live.SetLabels(nil)
+			},
+			want: []string{"metadata.labels.myresource", "spec.replicas"},
+		},
+		{
+			name: "case 4: sidecar injected",
+			mutate: func(live *appsv1.Deployment) {
+				live.Spec.Template.Spec.Containers = append(live.Spec.Template.Spec.Containers,
+					live.Spec.Template.Spec.Containers[0])
+			},
+			want: []string{"spec.template.spec.containers"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			live := desired.DeepCopy()
+			tt.mutate(live)
+			got, err := diffDeployments(live, desired)
+			if err != nil {
+				t.Fatalf("diffDeployments() error = %v", err)
+			}
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("diffDeployments() got = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,96 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+const (
+	_unmanagedDeploymentsCondition = "UnmanagedDeployments"
+
+	_labelCollisionReason   = "LabelCollision"
+	_duplicateDeletedReason = "DuplicateDeploymentDeleted"
+)
+
+// partitionDeployments sorts the Deployments labelled for myres into the one
+// it runs on, the stale duplicates it controls and the look-alikes created by
+// someone else.
+func partitionDeployments(
+	myres *mygroupv1alpha1.MyResource,
+	deploys []appsv1.Deployment,
+) (primary *appsv1.Deployment, stale, foreign []appsv1.Deployment) {
+	name := myres.GetName() + "-deployment"
+	for i := range deploys {
+		deploy := &deploys[i]
+		switch {
+		case !metav1.IsControlledBy(deploy, myres):
+			foreign = append(foreign, *deploy)
+		case deploy.GetName() == name:
+			primary = deploy
+		default:
+			stale = append(stale, *deploy)
+		}
+	}
+	return primary, stale, foreign
+}
+
+// deleteStaleDeployments deletes the duplicates controlled by myres, e.g. left
+// behind by a rename. The UID precondition guards against deleting a
+// Deployment recreated under the same name in the meantime.
+func (a *MyResourceReconciler) deleteStaleDeployments(
+	ctx context.Context,
+	myres *mygroupv1alpha1.MyResource,
+	stale []appsv1.Deployment,
+) error {
+	logger := log.FromContext(ctx)
+	for i := range stale {
+		deploy := &stale[i]
+		logger.Info("deleting duplicate deployment", "deployment", deploy.GetName())
+		err := a.Client.Delete(
+			ctx,
+			deploy,
+			client.Preconditions{UID: &deploy.UID},
+			client.PropagationPolicy(metav1.DeletePropagationBackground),
+		)
+		if client.IgnoreNotFound(err) != nil {
+			return err
+		}
+		a.Recorder.Event(myres, corev1.EventTypeNormal, _duplicateDeletedReason,
+			fmt.Sprintf("deleted duplicate deployment %s", deploy.GetName()))
+	}
+	return nil
+}
+
+// reportUnmanaged sets the UnmanagedDeployments condition when Deployments not
+// controlled by myres carry its label, they are left untouched.
+func reportUnmanaged(
+	myres *mygroupv1alpha1.MyResource,
+	status *mygroupv1alpha1.MyResourceStatus,
+	foreign []appsv1.Deployment,
+) {
+	if len(foreign) == 0 {
+		meta.RemoveStatusCondition(&status.Conditions, _unmanagedDeploymentsCondition)
+		return
+	}
+	names := make([]string, 0, len(foreign))
+	for _, deploy := range foreign {
+		names = append(names, deploy.GetName())
+	}
+	meta.SetStatusCondition(&status.Conditions, metav1.Condition{
+		Type:               _unmanagedDeploymentsCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: myres.GetGeneration(),
+		Reason:             _labelCollisionReason,
+		Message: fmt.Sprintf("deployments not managed by this resource carry the label myresource=%s: %s",
+			myres.GetName(), strings.Join(names, ", ")),
+	})
+}
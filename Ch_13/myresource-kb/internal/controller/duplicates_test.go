@@ -0,0 +1,113 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+)
+
+func Test_partitionDeployments(t *testing.T) {
+	myres := &mygroupv1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test", UID: types.UID("uid-1")},
+	}
+	ownerRef := metav1.NewControllerRef(myres, mygroupv1alpha1.GroupVersion.WithKind("MyResource"))
+	deploy := func(name string, owned bool) appsv1.Deployment {
+		d := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: name}}
+		if owned {
+			d.OwnerReferences = []metav1.OwnerReference{*ownerRef}
+		}
+		return d
+	}
+
+	tests := []struct {
+		name        string
+		deploys     []appsv1.Deployment
+		wantPrimary string
+		wantStale   int
+		wantForeign int
+	}{
+		{
+			name: "case 1: none",
+		},
+		{
+			name:        "case 2: only the primary",
+			deploys:     []appsv1.Deployment{deploy("test-deployment", true)},
+			wantPrimary: "test-deployment",
+		},
+		{
+			name: "case 3: owned duplicate",
+			deploys: []appsv1.Deployment{
+				deploy("test-old", true),
+				deploy("test-deployment", true),
+			},
+			wantPrimary: "test-deployment",
+			wantStale:   1,
+		},
+		{
+			name: "case 4: look-alike with the primary name",
+			deploys: []appsv1.Deployment{
+				deploy("test-deployment", false),
+				deploy("copy", false),
+			},
+			wantForeign: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			primary, stale, foreign := partitionDeployments(myres, tt.deploys)
+			gotPrimary := ""
+			if primary != nil {
+				gotPrimary = primary.GetName()
+			}
+			if gotPrimary != tt.wantPrimary {
+				t.Errorf("partitionDeployments() primary = %q, want %q", gotPrimary, tt.wantPrimary)
+			}
+			if len(stale) != tt.wantStale {
+				t.Errorf("partitionDeployments() stale = %d, want %d", len(stale), tt.wantStale)
+			}
+			if len(foreign) != tt.wantForeign {
+				t.Errorf("partitionDeployments() foreign = %d, want %d", len(foreign), tt.wantForeign)
+			}
+
+			status := &mygroupv1alpha1.MyResourceStatus{}
+			reportUnmanaged(myres, status, foreign)
+			if got := meta.IsStatusConditionTrue(status.Conditions, _unmanagedDeploymentsCondition); got != (tt.wantForeign > 0) {
+				t.Errorf("reportUnmanaged() condition = %v, want %v", got, tt.wantForeign > 0)
+			}
+		})
+	}
+}
+
+func Test_deleteStaleDeployments(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := appsv1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	myres := &mygroupv1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test"},
+	}
+	stale := &appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-old", UID: "uid-2"}}
+	r := &MyResourceReconciler{
+		Client:   fake.NewClientBuilder().WithScheme(scheme).WithObjects(stale).Build(),
+		Recorder: record.NewFakeRecorder(10),
+	}
+
+	// the second copy is already gone, which is not an error
+	gone := appsv1.Deployment{ObjectMeta: metav1.ObjectMeta{Namespace: "default", Name: "test-gone"}}
+	if err := r.deleteStaleDeployments(context.Background(), myres, []appsv1.Deployment{*stale, gone}); err != nil {
+		t.Fatalf("deleteStaleDeployments() error = %v", err)
+	}
+	err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(stale), &appsv1.Deployment{})
+	if client.IgnoreNotFound(err) != nil || err == nil {
+		t.Errorf("deleteStaleDeployments() left %s, get error = %v", stale.GetName(), err)
+	}
+}
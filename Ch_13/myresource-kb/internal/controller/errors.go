@@ -0,0 +1,123 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// errorClass decides how a failed reconcile is retried.
+type errorClass int
+
+const (
+	// _unknownError is retried with the slow backoff of the default class.
+	_unknownError errorClass = iota
+	// _transientError, e.g. an update conflict, is retried with a fast backoff.
+	_transientError
+	// _permanentError is reported in the status and not retried until the
+	// MyResource changes.
+	_permanentError
+	// _requeueAfterError is retried after a fixed delay, without backoff.
+	_requeueAfterError
+)
+
+const (
+	_degradedCondition = "Degraded"
+
+	_permanentErrorReason = "PermanentError"
+)
+
+// reconcileError carries the class of an error returned by the reconcile steps.
+type reconcileError struct {
+	class        errorClass
+	requeueAfter time.Duration
+	err          error
+}
+
+func (e *reconcileError) Error() string {
+	return e.err.Error()
+}
+
+func (e *reconcileError) Unwrap() error {
+	return e.err
+}
+
+// transientError marks err as worth a quick retry, for the failures that carry
+// no API status.
+func transientError(err error) error {
+	return &reconcileError{class: _transientError, err: err}
+}
+
+// permanentError marks err as not going away without a change of the MyResource.
+func permanentError(err error) error {
+	return &reconcileError{class: _permanentError, err: err}
+}
+
+// requeueAfterError marks err as to be retried after d.
+func requeueAfterError(err error, d time.Duration) error {
+	return &reconcileError{class: _requeueAfterError, requeueAfter: d, err: err}
+}
+
+// classify returns the class of err: the one it was marked with, otherwise
+// the one derived from the API status of err.
+func classify(err error) *reconcileError {
+	var rerr *reconcileError
+	if errors.As(err, &rerr) {
+		return rerr
+	}
+	switch {
+	case apierrors.IsConflict(err), apierrors.IsServerTimeout(err), apierrors.IsTimeout(err),
+		apierrors.IsTooManyRequests(err), apierrors.IsInternalError(err),
+		apierrors.IsServiceUnavailable(err), apierrors.IsUnexpectedServerError(err):
+		return &reconcileError{class: _transientError, err: err}
+	case apierrors.IsInvalid(err), apierrors.IsBadRequest(err), apierrors.IsRequestEntityTooLargeError(err):
+		return &reconcileError{class: _permanentError, err: err}
+	}
+	return &reconcileError{class: _unknownError, err: err}
+}
+
+// handleError turns the error of a reconcile into its result according to the
+// class of the error. myres is nil when the MyResource could not be read.
+func (a *MyResourceReconciler) handleError(
+	ctx context.Context,
+	req ctrl.Request,
+	myres *mygroupv1alpha1.MyResource,
+	err error,
+) (ctrl.Result, error) {
+	logger := log.FromContext(ctx)
+
+	rerr := classify(err)
+	a.rateLimiter.observe(req, rerr.class)
+	switch rerr.class {
+	case _requeueAfterError:
+		logger.Info("requeueing", "after", rerr.requeueAfter, "reason", err.Error())
+		return ctrl.Result{RequeueAfter: rerr.requeueAfter}, nil
+	case _permanentError:
+		if myres == nil {
+			return ctrl.Result{}, reconcile.TerminalError(err)
+		}
+		meta.SetStatusCondition(&myres.Status.Conditions, metav1.Condition{
+			Type:               _degradedCondition,
+			Status:             metav1.ConditionTrue,
+			ObservedGeneration: myres.GetGeneration(),
+			Reason:             _permanentErrorReason,
+			Message:            err.Error(),
+		})
+		a.Recorder.Event(myres, corev1.EventTypeWarning, _permanentErrorReason, err.Error())
+		if uerr := a.Client.Status().Update(ctx, myres); uerr != nil {
+			// the error is not lost, the retry reports it again
+			return ctrl.Result{}, uerr
+		}
+		return ctrl.Result{}, reconcile.TerminalError(err)
+	}
+	return ctrl.Result{}, err
+}
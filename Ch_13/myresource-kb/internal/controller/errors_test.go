@@ -0,0 +1,150 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"testing"
+	"time"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/tools/record"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+func Test_classify(t *testing.T) {
+	gr := schema.GroupResource{Group: "apps", Resource: "deployments"}
+	tests := []struct {
+		name string
+		err  error
+		want errorClass
+	}{
+		{
+			name: "case 1: conflict",
+			err:  apierrors.NewConflict(gr, "test", errors.New("modified")),
+			want: _transientError,
+		},
+		{
+			name: "case 2: invalid",
+			err:  apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, "test", nil),
+			want: _permanentError,
+		},
+		{
+			name: "case 3: marked",
+			err:  fmt.Errorf("applying: %w", requeueAfterError(errors.New("inconsistent"), time.Minute)),
+			want: _requeueAfterError,
+		},
+		{
+			name: "case 4: plain error",
+			err:  errors.New("connection refused"),
+			want: _unknownError,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := classify(tt.err); got.class != tt.want {
+				t.Errorf("classify() = %v, want %v", got.class, tt.want)
+			}
+		})
+	}
+}
+
+func Test_classRateLimiter(t *testing.T) {
+	item := reconcile.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test"}}
+	limiter := newClassRateLimiter()
+
+	limiter.observe(item, _transientError)
+	if got := limiter.When(item); got != 5*time.Millisecond {
+		t.Errorf("When() transient = %v, want %v", got, 5*time.Millisecond)
+	}
+	limiter.observe(item, _unknownError)
+	if got := limiter.When(item); got != time.Second {
+		t.Errorf("When() unknown = %v, want %v", got, time.Second)
+	}
+	if got := limiter.When(item); got != 2*time.Second {
+		t.Errorf("When() unknown again = %v, want %v", got, 2*time.Second)
+	}
+
+	limiter.Forget(item)
+	limiter.observe(item, _unknownError)
+	if got := limiter.When(item); got != time.Second {
+		t.Errorf("When() after Forget = %v, want %v", got, time.Second)
+	}
+}
+
+func Test_handleError(t *testing.T) {
+	scheme := runtime.NewScheme()
+	if err := mygroupv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	req := ctrl.Request{NamespacedName: types.NamespacedName{Namespace: "default", Name: "test"}}
+
+	tests := []struct {
+		name         string
+		err          error
+		wantTerminal bool
+		wantRequeue  time.Duration
+		wantDegraded bool
+	}{
+		{
+			name:         "case 1: permanent",
+			err:          permanentError(errors.New("bad spec")),
+			wantTerminal: true,
+			wantDegraded: true,
+		},
+		{
+			name:        "case 2: requeue after",
+			err:         requeueAfterError(errors.New("2 deployment found"), time.Minute),
+			wantRequeue: time.Minute,
+		},
+		{
+			name: "case 3: transient",
+			err:  transientError(errors.New("conflict")),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			myres := &mygroupv1alpha1.MyResource{
+				ObjectMeta: metav1.ObjectMeta{Namespace: req.Namespace, Name: req.Name},
+			}
+			r := &MyResourceReconciler{
+				Client: fake.NewClientBuilder().
+					WithScheme(scheme).
+					WithObjects(myres).
+					WithStatusSubresource(myres).
+					Build(),
+				Scheme:      scheme,
+				Recorder:    record.NewFakeRecorder(10),
+				rateLimiter: newClassRateLimiter(),
+			}
+
+			result, err := r.handleError(context.Background(), req, myres.DeepCopy(), tt.err)
+			if got := errors.Is(err, reconcile.TerminalError(nil)); got != tt.wantTerminal {
+				t.Errorf("handleError() terminal = %v, want %v", got, tt.wantTerminal)
+			}
+			if !tt.wantTerminal && tt.wantRequeue == 0 && err == nil {
+				t.Errorf("handleError() error = nil, want the error to be retried")
+			}
+			if result.RequeueAfter != tt.wantRequeue {
+				t.Errorf("handleError() requeueAfter = %v, want %v", result.RequeueAfter, tt.wantRequeue)
+			}
+
+			stored := &mygroupv1alpha1.MyResource{}
+			if err := r.Client.Get(context.Background(), client.ObjectKeyFromObject(myres), stored); err != nil {
+				t.Fatal(err)
+			}
+			if got := meta.IsStatusConditionTrue(stored.Status.Conditions, _degradedCondition); got != tt.wantDegraded {
+				t.Errorf("handleError() degraded = %v, want %v", got, tt.wantDegraded)
+			}
+		})
+	}
+}
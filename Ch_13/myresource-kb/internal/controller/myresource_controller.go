@@ -19,13 +19,24 @@ package controller
 import (
 	"context"
 
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/trace"
 	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/client-go/tools/record"
 	ctrl "sigs.k8s.io/controller-runtime"
 	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/controller"
+	"sigs.k8s.io/controller-runtime/pkg/event"
+	"sigs.k8s.io/controller-runtime/pkg/handler"
 	"sigs.k8s.io/controller-runtime/pkg/log"
+	"sigs.k8s.io/controller-runtime/pkg/source"
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"github.com/myid/myresource/internal/sharding"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
 	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
 	"sigs.k8s.io/controller-runtime/pkg/reconcile"
 )
@@ -33,13 +44,27 @@ import (
 // MyResourceReconciler reconciles a MyResource object
 type MyResourceReconciler struct {
 	client.Client
-	Scheme *runtime.Scheme
+	Scheme   *runtime.Scheme
+	Recorder record.EventRecorder
+	// Sharder, when set, restricts the reconciliation to the MyResources
+	// owned by this replica of the manager.
+	Sharder *sharding.Sharder
+	// rateLimiter backs off failed reconciles according to their error class.
+	rateLimiter *classRateLimiter
+	// Tunables, when set, holds the concurrency and requeue interval that can
+	// be changed at runtime through the configuration file.
+	Tunables *Tunables
+	// TracerProvider, when set, replaces the global OpenTelemetry provider.
+	TracerProvider trace.TracerProvider
 }
 
 // +kubebuilder:rbac:groups=mygroup.myid.dev,resources=myresources,verbs=get;list;watch;create;update;patch;delete
 // +kubebuilder:rbac:groups=mygroup.myid.dev,resources=myresources/status,verbs=get;update;patch
 // +kubebuilder:rbac:groups=mygroup.myid.dev,resources=myresources/finalizers,verbs=update
 // +kubebuilder:rbac:groups=apps,resources=deployments,verbs=get;list;watch;create;update;patch;delete
+// +kubebuilder:rbac:groups=apps,resources=replicasets,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=pods,verbs=get;list;watch
+// +kubebuilder:rbac:groups=core,resources=events,verbs=create;patch
 
 // Reconcile is part of the main kubernetes reconciliation loop which aims to
 // move the current state of the cluster closer to the desired state.
@@ -51,15 +76,36 @@ type MyResourceReconciler struct {
 // For more details, check Reconcile and its Result here:
 // - https://pkg.go.dev/sigs.k8s.io/controller-runtime@v0.19.0/pkg/reconcile
 func (r *MyResourceReconciler) Reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
+	ctx, span := r.tracer().Start(ctx, "Reconcile", trace.WithAttributes(
+		attribute.String(_attrName, req.Name),
+		attribute.String(_attrNamespace, req.Namespace),
+	))
+	result, err := r.reconcile(ctx, req)
+	endSpan(span, err)
+	return result, err
+}
+
+// reconcile does the work of Reconcile, within its span.
+func (r *MyResourceReconciler) reconcile(ctx context.Context, req ctrl.Request) (ctrl.Result, error) {
 	// _ = log.FromContext(ctx)
 
 	// TODO(user): your logic here
 	logger := log.FromContext(ctx)
+	if r.Sharder != nil && !r.Sharder.Owns(req.NamespacedName) {
+		logger.V(1).Info("myresource is owned by another shard")
+		return reconcile.Result{}, nil
+	}
+	release, err := r.Tunables.acquire(ctx)
+	if err != nil {
+		return reconcile.Result{}, err
+	}
+	defer release()
+
 	logger.Info("getting myresource instance")
 
 	// reuse
 	myRes := mygroupv1alpha1.MyResource{}
-	err := r.Client.Get(
+	err = r.Client.Get(
 		ctx,
 		req.NamespacedName,
 		&myRes,
@@ -70,33 +116,103 @@ func (r *MyResourceReconciler) Reconcile(ctx context.Context, req ctrl.Request)
 			logger.Info("resource is not found")
 			return reconcile.Result{}, nil
 		}
+		return r.handleError(ctx, req, nil, err)
+	}
+	trace.SpanFromContext(ctx).SetAttributes(attribute.Int64(_attrGeneration, myRes.Generation))
+
+	setPauseConditions(&myRes)
+	if myRes.Spec.Paused {
+		logger.Info("reconciliation is paused")
+		if err = r.updateStatus(ctx, &myRes); err != nil {
+			return r.handleError(ctx, req, nil, err)
+		}
+		return reconcile.Result{}, nil
+	}
+
+	if err := validateSpec(&myRes); err != nil {
+		return r.handleError(ctx, req, &myRes, err)
 	}
 
 	ownerRef := metav1.NewControllerRef(&myRes, mygroupv1alpha1.GroupVersion.WithKind("MyResource"))
 
-	err = r.applyDeployment(ctx, &myRes, ownerRef)
+	image, canaryRequeue, err := r.reconcileCanary(ctx, &myRes, ownerRef)
 	if err != nil {
-		return reconcile.Result{}, err
+		return r.handleError(ctx, req, &myRes, err)
 	}
 
-	status, err := r.computeStatus(ctx, &myRes)
+	spanCtx, span := r.tracer().Start(ctx, "applyDeployment")
+	drift, err := r.applyDeployment(spanCtx, &myRes, ownerRef, image)
+	endSpan(span, err)
 	if err != nil {
-		return reconcile.Result{}, err
+		return r.handleError(ctx, req, &myRes, err)
+	}
+
+	spanCtx, span = r.tracer().Start(ctx, "computeStatus")
+	status, err := r.computeStatus(spanCtx, &myRes)
+	endSpan(span, err)
+	if err != nil {
+		return r.handleError(ctx, req, &myRes, err)
 	}
 
 	myRes.Status = *status
+	r.reportDrift(&myRes, drift)
+	meta.RemoveStatusCondition(&myRes.Status.Conditions, _degradedCondition)
 	logger.Info("updating status", "state", status.State)
-	err = r.Client.Status().Update(ctx, &myRes)
+	err = r.updateStatus(ctx, &myRes)
 	if err != nil {
-		return reconcile.Result{}, err
+		return r.handleError(ctx, req, nil, err)
 	}
 
-	return ctrl.Result{}, nil
+	return ctrl.Result{RequeueAfter: requeueAfter(canaryRequeue, r.Tunables.RequeueInterval())}, nil
 }
 
-// SetupWithManager sets up the controller with the Manager.
+// SetupWithManager sets up the controller with the Manager. Only the Pods and
+// ReplicaSets labelled myresource are read, the cache of mgr should be
+// restricted to them so that it does not hold all the Pods of the cluster.
 func (r *MyResourceReconciler) SetupWithManager(mgr ctrl.Manager) error {
-	return ctrl.NewControllerManagedBy(mgr).
+	blder := ctrl.NewControllerManagedBy(mgr).
 		For(&mygroupv1alpha1.MyResource{}).
-		Complete(r)
+		Owns(&appsv1.Deployment{}).
+		Watches(&corev1.Pod{}, handler.EnqueueRequestsFromMapFunc(podToMyResource))
+
+	r.rateLimiter = newClassRateLimiter()
+	options := controller.Options{RateLimiter: r.rateLimiter}
+	if r.Tunables != nil {
+		options.MaxConcurrentReconciles = r.Tunables.Limit()
+	}
+	blder = blder.WithOptions(options)
+
+	if r.Sharder != nil {
+		// objects moving to this replica after a rebalance get no watch event,
+		// they are enqueued through this channel instead
+		rebalanced := make(chan event.GenericEvent)
+		r.Sharder.OnRebalance = func(ctx context.Context) {
+			r.enqueueOwned(ctx, rebalanced)
+		}
+		blder = blder.WatchesRawSource(source.Channel(rebalanced, &handler.EnqueueRequestForObject{}))
+	}
+
+	return blder.Complete(r)
+}
+
+// enqueueOwned sends every MyResource owned by this replica to the controller.
+func (r *MyResourceReconciler) enqueueOwned(ctx context.Context, events chan<- event.GenericEvent) {
+	logger := log.FromContext(ctx)
+
+	myResList := mygroupv1alpha1.MyResourceList{}
+	if err := r.Client.List(ctx, &myResList); err != nil {
+		logger.Error(err, "unable to list myresources after rebalance")
+		return
+	}
+	for i := range myResList.Items {
+		myRes := &myResList.Items[i]
+		if !r.Sharder.Owns(client.ObjectKeyFromObject(myRes)) {
+			continue
+		}
+		select {
+		case events <- event.GenericEvent{Object: myRes}:
+		case <-ctx.Done():
+			return
+		}
+	}
 }
@@ -0,0 +1,48 @@
+package controller
+
+import (
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+const (
+	_pausedCondition    = "Paused"
+	_suspendedCondition = "Suspended"
+
+	_pausedReason      = "ReconciliationPaused"
+	_reconcilingReason = "Reconciling"
+	_suspendedReason   = "ScaledToZero"
+	_runningReason     = "Running"
+)
+
+// setPauseConditions reflects spec.paused and spec.suspend in the conditions of myres.
+func setPauseConditions(myres *mygroupv1alpha1.MyResource) {
+	paused := metav1.Condition{
+		Type:               _pausedCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: myres.GetGeneration(),
+		Reason:             _reconcilingReason,
+		Message:            "the operator reconciles the owned Deployment",
+	}
+	if myres.Spec.Paused {
+		paused.Status = metav1.ConditionTrue
+		paused.Reason = _pausedReason
+		paused.Message = "reconciliation is paused, the owned Deployment is left untouched"
+	}
+	meta.SetStatusCondition(&myres.Status.Conditions, paused)
+
+	suspended := metav1.Condition{
+		Type:               _suspendedCondition,
+		Status:             metav1.ConditionFalse,
+		ObservedGeneration: myres.GetGeneration(),
+		Reason:             _runningReason,
+		Message:            "the owned Deployment is scaled up",
+	}
+	if myres.Spec.Suspend {
+		suspended.Status = metav1.ConditionTrue
+		suspended.Reason = _suspendedReason
+		suspended.Message = "the owned Deployment is scaled to zero replicas"
+	}
+	meta.SetStatusCondition(&myres.Status.Conditions, suspended)
+}
@@ -0,0 +1,54 @@
+package controller
+
+import (
+	"testing"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_setPauseConditions(t *testing.T) {
+	tests := []struct {
+		name          string
+		paused        bool
+		suspend       bool
+		wantPaused    metav1.ConditionStatus
+		wantSuspended metav1.ConditionStatus
+	}{
+		{
+			name:          "case 1: running",
+			wantPaused:    metav1.ConditionFalse,
+			wantSuspended: metav1.ConditionFalse,
+		},
+		{
+			name:          "case 2: paused",
+			paused:        true,
+			wantPaused:    metav1.ConditionTrue,
+			wantSuspended: metav1.ConditionFalse,
+		},
+		{
+			name:          "case 3: suspended",
+			suspend:       true,
+			wantPaused:    metav1.ConditionFalse,
+			wantSuspended: metav1.ConditionTrue,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			myres := &mygroupv1alpha1.MyResource{
+				Spec: mygroupv1alpha1.MyResourceSpec{
+					Paused:  tt.paused,
+					Suspend: tt.suspend,
+				},
+			}
+			setPauseConditions(myres)
+			if got := meta.FindStatusCondition(myres.Status.Conditions, _pausedCondition); got == nil || got.Status != tt.wantPaused {
+				t.Errorf("setPauseConditions() paused = %v, want %v", got, tt.wantPaused)
+			}
+			if got := meta.FindStatusCondition(myres.Status.Conditions, _suspendedCondition); got == nil || got.Status != tt.wantSuspended {
+				t.Errorf("setPauseConditions() suspended = %v, want %v", got, tt.wantSuspended)
+			}
+		})
+	}
+}
@@ -0,0 +1,201 @@
+package controller
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"strings"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+const (
+	_podsHealthyCondition = "PodsHealthy"
+
+	_allPodsHealthyReason = "AllPodsHealthy"
+	_crashLoopReason      = "CrashLoopBackOff"
+	_imagePullReason      = "ImagePullBackOff"
+	_errImagePullReason   = "ErrImagePull"
+	_oomKilledReason      = "OOMKilled"
+	_unschedulableReason  = "Unschedulable"
+
+	// _maxPodIssues bounds status.podIssues, the condition still counts them all
+	_maxPodIssues = 10
+
+	_revisionAnnotation = "deployment.kubernetes.io/revision"
+)
+
+// _podIssueReasons orders the reasons by severity, the condition reports the
+// most frequent one and the first in this list on a tie.
+var _podIssueReasons = []string{
+	_oomKilledReason,
+	_crashLoopReason,
+	_imagePullReason,
+	_errImagePullReason,
+	_unschedulableReason,
+}
+
+// currentPods returns the Pods of the ReplicaSet of the current revision of
+// deploy, the Pods of older ReplicaSets being scaled down anyway.
+func (a *MyResourceReconciler) currentPods(
+	ctx context.Context,
+	deploy *appsv1.Deployment,
+) ([]corev1.Pod, error) {
+	if deploy.Spec.Selector == nil {
+		return nil, nil
+	}
+	rsList := appsv1.ReplicaSetList{}
+	err := a.Client.List(
+		ctx,
+		&rsList,
+		client.InNamespace(deploy.GetNamespace()),
+		client.MatchingLabels(deploy.Spec.Selector.MatchLabels),
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	revision := deploy.GetAnnotations()[_revisionAnnotation]
+	var current *appsv1.ReplicaSet
+	for i := range rsList.Items {
+		rs := &rsList.Items[i]
+		if metav1.IsControlledBy(rs, deploy) && rs.GetAnnotations()[_revisionAnnotation] == revision {
+			current = rs
+			break
+		}
+	}
+	if current == nil || current.Spec.Selector == nil {
+		return nil, nil
+	}
+
+	podList := corev1.PodList{}
+	err = a.Client.List(
+		ctx,
+		&podList,
+		client.InNamespace(deploy.GetNamespace()),
+		client.MatchingLabels(current.Spec.Selector.MatchLabels),
+	)
+	if err != nil {
+		return nil, err
+	}
+	pods := make([]corev1.Pod, 0, len(podList.Items))
+	for _, pod := range podList.Items {
+		if metav1.IsControlledBy(&pod, current) {
+			pods = append(pods, pod)
+		}
+	}
+	return pods, nil
+}
+
+// podIssues returns the known problems of pods, sorted by Pod and container.
+func podIssues(pods []corev1.Pod) []mygroupv1alpha1.PodIssue {
+	var issues []mygroupv1alpha1.PodIssue
+	for _, pod := range pods {
+		if pod.Status.Phase == corev1.PodPending {
+			for _, c := range pod.Status.Conditions {
+				if c.Type == corev1.PodScheduled && c.Status == corev1.ConditionFalse && c.Reason == corev1.PodReasonUnschedulable {
+					issues = append(issues, mygroupv1alpha1.PodIssue{
+						Pod:     pod.GetName(),
+						Reason:  _unschedulableReason,
+						Message: c.Message,
+					})
+				}
+			}
+		}
+		statuses := append(append([]corev1.ContainerStatus{}, pod.Status.InitContainerStatuses...),
+			pod.Status.ContainerStatuses...)
+		for _, cs := range statuses {
+			if issue := containerIssue(cs); issue != nil {
+				issue.Pod = pod.GetName()
+				issues = append(issues, *issue)
+			}
+		}
+	}
+	sort.SliceStable(issues, func(i, j int) bool {
+		if issues[i].Pod != issues[j].Pod {
+			return issues[i].Pod < issues[j].Pod
+		}
+		return issues[i].Container < issues[j].Container
+	})
+	return issues
+}
+
+// containerIssue returns the problem of a container, an OOM kill taking
+// precedence over the back-off it causes.
+func containerIssue(cs corev1.ContainerStatus) *mygroupv1alpha1.PodIssue {
+	for _, terminated := range []*corev1.ContainerStateTerminated{cs.State.Terminated, cs.LastTerminationState.Terminated} {
+		if terminated != nil && terminated.Reason == _oomKilledReason {
+			return &mygroupv1alpha1.PodIssue{
+				Container: cs.Name,
+				Reason:    _oomKilledReason,
+				Message:   fmt.Sprintf("container exceeded its memory limit, restarted %d times", cs.RestartCount),
+			}
+		}
+	}
+	if waiting := cs.State.Waiting; waiting != nil {
+		switch waiting.Reason {
+		case _crashLoopReason, _imagePullReason, _errImagePullReason:
+			return &mygroupv1alpha1.PodIssue{
+				Container: cs.Name,
+				Reason:    waiting.Reason,
+				Message:   waiting.Message,
+			}
+		}
+	}
+	return nil
+}
+
+// podToMyResource maps a Pod to the MyResource it runs for, so that a Pod
+// crashing without changing the Deployment status still updates the MyResource.
+func podToMyResource(_ context.Context, obj client.Object) []reconcile.Request {
+	name, ok := obj.GetLabels()["myresource"]
+	if !ok {
+		return nil
+	}
+	return []reconcile.Request{{
+		NamespacedName: types.NamespacedName{Namespace: obj.GetNamespace(), Name: name},
+	}}
+}
+
+// setPodHealth reports issues in status.podIssues and the PodsHealthy condition.
+func setPodHealth(
+	myres *mygroupv1alpha1.MyResource,
+	status *mygroupv1alpha1.MyResourceStatus,
+	issues []mygroupv1alpha1.PodIssue,
+) {
+	condition := metav1.Condition{
+		Type:               _podsHealthyCondition,
+		Status:             metav1.ConditionTrue,
+		ObservedGeneration: myres.GetGeneration(),
+		Reason:             _allPodsHealthyReason,
+		Message:            "no pod reports a known issue",
+	}
+	status.PodIssues = nil
+	if len(issues) > 0 {
+		counts := map[string]int{}
+		for _, issue := range issues {
+			counts[issue.Reason]++
+		}
+		var summary []string
+		for _, reason := range _podIssueReasons {
+			if counts[reason] == 0 {
+				continue
+			}
+			if counts[reason] > counts[condition.Reason] {
+				condition.Reason = reason
+			}
+			summary = append(summary, fmt.Sprintf("%d %s", counts[reason], reason))
+		}
+		condition.Status = metav1.ConditionFalse
+		condition.Message = fmt.Sprintf("pod issues: %s", strings.Join(summary, ", "))
+		status.PodIssues = issues[:min(len(issues), _maxPodIssues)]
+	}
+	meta.SetStatusCondition(&status.Conditions, condition)
+}
@@ -0,0 +1,129 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_podIssues(t *testing.T) {
+	pod := func(name string, status corev1.PodStatus) corev1.Pod {
+		return corev1.Pod{ObjectMeta: metav1.ObjectMeta{Name: name}, Status: status}
+	}
+	waiting := func(container, reason string) corev1.ContainerStatus {
+		return corev1.ContainerStatus{
+			Name:  container,
+			State: corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: reason, Message: "msg"}},
+		}
+	}
+
+	tests := []struct {
+		name string
+		pods []corev1.Pod
+		want []mygroupv1alpha1.PodIssue
+	}{
+		{
+			name: "case 1: healthy",
+			pods: []corev1.Pod{pod("p1", corev1.PodStatus{
+				Phase:             corev1.PodRunning,
+				ContainerStatuses: []corev1.ContainerStatus{{Name: "main", Ready: true}},
+			})},
+		},
+		{
+			name: "case 2: crash loop and image pull",
+			pods: []corev1.Pod{
+				pod("p2", corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{waiting("main", _imagePullReason)}}),
+				pod("p1", corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{waiting("main", _crashLoopReason)}}),
+			},
+			want: []mygroupv1alpha1.PodIssue{
+				{Pod: "p1", Container: "main", Reason: _crashLoopReason, Message: "msg"},
+				{Pod: "p2", Container: "main", Reason: _imagePullReason, Message: "msg"},
+			},
+		},
+		{
+			name: "case 3: OOM kill takes precedence over the back-off",
+			pods: []corev1.Pod{pod("p1", corev1.PodStatus{ContainerStatuses: []corev1.ContainerStatus{{
+				Name:                 "main",
+				RestartCount:         3,
+				State:                corev1.ContainerState{Waiting: &corev1.ContainerStateWaiting{Reason: _crashLoopReason}},
+				LastTerminationState: corev1.ContainerState{Terminated: &corev1.ContainerStateTerminated{Reason: _oomKilledReason}},
+			}}})},
+			want: []mygroupv1alpha1.PodIssue{
+				{Pod: "p1", Container: "main", Reason: _oomKilledReason, Message: "container exceeded its memory limit, restarted 3 times"},
+			},
+		},
+		{
+			name: "case 4: unschedulable",
+			pods: []corev1.Pod{pod("p1", corev1.PodStatus{
+				Phase: corev1.PodPending,
+				Conditions: []corev1.PodCondition{{
+					Type:    corev1.PodScheduled,
+					Status:  corev1.ConditionFalse,
+					Reason:  corev1.PodReasonUnschedulable,
+					Message: "0/3 nodes are available: 3 Insufficient memory.",
+				}},
+			})},
+			want: []mygroupv1alpha1.PodIssue{
+				{Pod: "p1", Reason: _unschedulableReason, Message: "0/3 nodes are available: 3 Insufficient memory."},
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := podIssues(tt.pods); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("podIssues() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_setPodHealth(t *testing.T) {
+	issue := func(pod, reason string) mygroupv1alpha1.PodIssue {
+		return mygroupv1alpha1.PodIssue{Pod: pod, Container: "main", Reason: reason}
+	}
+	tests := []struct {
+		name        string
+		issues      []mygroupv1alpha1.PodIssue
+		wantStatus  metav1.ConditionStatus
+		wantReason  string
+		wantMessage string
+	}{
+		{
+			name:        "case 1: healthy",
+			wantStatus:  metav1.ConditionTrue,
+			wantReason:  _allPodsHealthyReason,
+			wantMessage: "no pod reports a known issue",
+		},
+		{
+			name:        "case 2: most frequent reason",
+			issues:      []mygroupv1alpha1.PodIssue{issue("p1", _oomKilledReason), issue("p2", _crashLoopReason), issue("p3", _crashLoopReason)},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  _crashLoopReason,
+			wantMessage: "pod issues: 1 OOMKilled, 2 CrashLoopBackOff",
+		},
+		{
+			name:        "case 3: most severe on a tie",
+			issues:      []mygroupv1alpha1.PodIssue{issue("p1", _unschedulableReason), issue("p2", _oomKilledReason)},
+			wantStatus:  metav1.ConditionFalse,
+			wantReason:  _oomKilledReason,
+			wantMessage: "pod issues: 1 OOMKilled, 1 Unschedulable",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			status := &mygroupv1alpha1.MyResourceStatus{}
+			setPodHealth(&mygroupv1alpha1.MyResource{}, status, tt.issues)
+			got := meta.FindStatusCondition(status.Conditions, _podsHealthyCondition)
+			if got == nil || got.Status != tt.wantStatus || got.Reason != tt.wantReason || got.Message != tt.wantMessage {
+				t.Errorf("setPodHealth() condition = %v, want %v %s %q", got, tt.wantStatus, tt.wantReason, tt.wantMessage)
+			}
+			if len(status.PodIssues) != len(tt.issues) {
+				t.Errorf("setPodHealth() podIssues = %d, want %d", len(status.PodIssues), len(tt.issues))
+			}
+		})
+	}
+}
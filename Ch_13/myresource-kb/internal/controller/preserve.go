@@ -0,0 +1,202 @@
+package controller
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"strings"
+
+	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+)
+
+// withoutFields returns the apply configuration of deploy without the fields
+// at paths, so that applying it without force leaves them to their managers.
+// The paths are in the format of apply conflicts, e.g.
+// .spec.template.spec.containers[name="main"].image.
+func withoutFields(deploy *appsv1.Deployment, paths []string) (*unstructured.Unstructured, error) {
+	obj, err := runtime.DefaultUnstructuredConverter.ToUnstructured(deploy)
+	if err != nil {
+		return nil, err
+	}
+	for _, path := range paths {
+		elements, err := parseFieldPath(path)
+		if err != nil {
+			return nil, err
+		}
+		removeField(obj, elements)
+	}
+	return &unstructured.Unstructured{Object: obj}, nil
+}
+
+// pathElement is a step of a field path: a field name, the keys of an item
+// of an associative list, the value of an item of a set or a list index.
+type pathElement struct {
+	field string
+	keys  map[string]interface{}
+	value interface{}
+	index int
+	kind  byte // '.', 'k', '=' or 'i'
+}
+
+// parseFieldPath parses a field path as printed by structured-merge-diff.
+func parseFieldPath(path string) ([]pathElement, error) {
+	var elements []pathElement
+	for i := 0; i < len(path); {
+		switch path[i] {
+		case '.':
+			end := i + 1
+			for end < len(path) && path[end] != '.' && path[end] != '[' {
+				end++
+			}
+			elements = append(elements, pathElement{kind: '.', field: path[i+1 : end]})
+			i = end
+		case '[':
+			end, err := closingBracket(path, i)
+			if err != nil {
+				return nil, err
+			}
+			element, err := parseSelector(path[i+1 : end])
+			if err != nil {
+				return nil, fmt.Errorf("field path %s: %w", path, err)
+			}
+			elements = append(elements, element)
+			i = end + 1
+		default:
+			return nil, fmt.Errorf("field path %s: unexpected %q at %d", path, path[i], i)
+		}
+	}
+	return elements, nil
+}
+
+// closingBracket returns the index of the bracket closing the one at start,
+// skipping the brackets in quoted values.
+func closingBracket(path string, start int) (int, error) {
+	quoted := false
+	for i := start + 1; i < len(path); i++ {
+		switch {
+		case quoted && path[i] == '\\':
+			i++
+		case path[i] == '"':
+			quoted = !quoted
+		case !quoted && path[i] == ']':
+			return i, nil
+		}
+	}
+	return 0, fmt.Errorf("field path %s: unterminated [ at %d", path, start)
+}
+
+// parseSelector parses the content of brackets: =value, an index or
+// comma-separated key=value pairs, the values being JSON.
+func parseSelector(selector string) (pathElement, error) {
+	if value, found := strings.CutPrefix(selector, "="); found {
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return pathElement{}, err
+		}
+		return pathElement{kind: '=', value: v}, nil
+	}
+	if index, err := strconv.Atoi(selector); err == nil {
+		return pathElement{kind: 'i', index: index}, nil
+	}
+	keys := map[string]interface{}{}
+	for _, pair := range splitUnquoted(selector, ',') {
+		name, value, found := strings.Cut(pair, "=")
+		if !found {
+			return pathElement{}, fmt.Errorf("invalid key %q", pair)
+		}
+		var v interface{}
+		if err := json.Unmarshal([]byte(value), &v); err != nil {
+			return pathElement{}, err
+		}
+		keys[name] = v
+	}
+	return pathElement{kind: 'k', keys: keys}, nil
+}
+
+// splitUnquoted splits s around the separators that are not in quoted values.
+func splitUnquoted(s string, sep byte) []string {
+	var parts []string
+	quoted, start := false, 0
+	for i := 0; i < len(s); i++ {
+		switch {
+		case quoted && s[i] == '\\':
+			i++
+		case s[i] == '"':
+			quoted = !quoted
+		case !quoted && s[i] == sep:
+			parts = append(parts, s[start:i])
+			start = i + 1
+		}
+	}
+	return append(parts, s[start:])
+}
+
+// removeField removes the field at elements from the unstructured obj, if it
+// is there.
+func removeField(obj interface{}, elements []pathElement) interface{} {
+	if len(elements) == 0 {
+		return obj
+	}
+	element, last := elements[0], len(elements) == 1
+	switch element.kind {
+	case '.':
+		m, ok := obj.(map[string]interface{})
+		if !ok {
+			return obj
+		}
+		if last {
+			delete(m, element.field)
+		} else if child, found := m[element.field]; found {
+			m[element.field] = removeField(child, elements[1:])
+		}
+		return m
+	default:
+		items, ok := obj.([]interface{})
+		if !ok {
+			return obj
+		}
+		for i, item := range items {
+			if !element.matches(i, item) {
+				continue
+			}
+			if last {
+				return append(items[:i:i], items[i+1:]...)
+			}
+			items[i] = removeField(item, elements[1:])
+			return items
+		}
+		return items
+	}
+}
+
+// matches tells whether the item at index i of a list is selected by e.
+func (e pathElement) matches(i int, item interface{}) bool {
+	switch e.kind {
+	case 'i':
+		return i == e.index
+	case '=':
+		return jsonEqual(item, e.value)
+	default:
+		m, ok := item.(map[string]interface{})
+		if !ok {
+			return false
+		}
+		for name, value := range e.keys {
+			if !jsonEqual(m[name], value) {
+				return false
+			}
+		}
+		return true
+	}
+}
+
+// jsonEqual compares values through their JSON encoding, numbers being int64
+// in unstructured objects and float64 when decoded from a path.
+func jsonEqual(a, b interface{}) bool {
+	aJSON, errA := json.Marshal(a)
+	bJSON, errB := json.Marshal(b)
+	return errA == nil && errB == nil && reflect.DeepEqual(aJSON, bJSON)
+}
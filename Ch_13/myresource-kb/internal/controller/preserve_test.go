@@ -0,0 +1,215 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	corev1 "k8s.io/api/core/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func Test_withoutFields(t *testing.T) {
+	myres := newTestMyResource()
+	deploy := createDeployment(myres,
+		metav1.NewControllerRef(myres, mygroupv1alpha1.GroupVersion.WithKind("MyResource")), myres.Spec.Image)
+	deploy.Spec.Template.Spec.Containers[0].Ports = []corev1.ContainerPort{
+		{ContainerPort: 80, Protocol: corev1.ProtocolTCP},
+		{ContainerPort: 443, Protocol: corev1.ProtocolTCP},
+	}
+
+	tests := []struct {
+		name    string
+		paths   []string
+		removed [][]string
+		kept    [][]string
+		wantErr bool
+	}{
+		{
+			name:    "case 1: field",
+			paths:   []string{".spec.replicas"},
+			removed: [][]string{{"spec", "replicas"}},
+			kept:    [][]string{{"spec", "selector"}},
+		},
+		{
+			name:    "case 2: field of an associative list item",
+			paths:   []string{`.spec.template.spec.containers[name="main"].image`},
+			removed: [][]string{{"spec", "template", "spec", "containers", "0", "image"}},
+			kept:    [][]string{{"spec", "template", "spec", "containers", "0", "resources"}},
+		},
+		{
+			name:    "case 3: item of a list with a composite key",
+			paths:   []string{`.spec.template.spec.containers[name="main"].ports[containerPort=80,protocol="TCP"]`},
+			removed: [][]string{{"spec", "template", "spec", "containers", "0", "ports", "1"}},
+			kept:    [][]string{{"spec", "template", "spec", "containers", "0", "ports", "0"}},
+		},
+		{
+			name:  "case 4: unknown item",
+			paths: []string{`.spec.template.spec.containers[name="sidecar"].image`},
+			kept:  [][]string{{"spec", "template", "spec", "containers", "0", "image"}},
+		},
+		{
+			name:    "case 5: invalid path",
+			paths:   []string{`.spec.template.spec.containers[name="main"`},
+			wantErr: true,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, err := withoutFields(deploy, tt.paths)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("withoutFields() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			for _, path := range tt.removed {
+				if _, found := lookup(got.Object, path); found {
+					t.Errorf("withoutFields() kept %v", path)
+				}
+			}
+			for _, path := range tt.kept {
+				if _, found := lookup(got.Object, path); !found {
+					t.Errorf("withoutFields() removed %v", path)
+				}
+			}
+		})
+	}
+	if deploy.Spec.Replicas == nil || deploy.Spec.Template.Spec.Containers[0].Image == "" {
+		t.Errorf("withoutFields() changed the desired Deployment")
+	}
+}
+
+// lookup returns the value at path in obj, list indexes being numbers.
+func lookup(obj interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		switch o := obj.(type) {
+		case map[string]interface{}:
+			value, found := o[key]
+			if !found {
+				return nil, false
+			}
+			obj = value
+		case []interface{}:
+			i := int(key[0] - '0')
+			if i >= len(o) {
+				return nil, false
+			}
+			obj = o[i]
+		default:
+			return nil, false
+		}
+	}
+	return obj, true
+}
+
+func Test_applyDeployment_preserve(t *testing.T) {
+	myres := newTestMyResource()
+	myres.Spec.DriftPolicy = mygroupv1alpha1.DriftPolicyPreserve
+	myres.Spec.Image = "nginx:1.28"
+	live := ownedDeployment(myres, myres.Name+"-deployment")
+	live.Spec.Replicas = ptr.To[int32](3)
+	live.Spec.Template.Spec.Containers[0].Image = "nginx:1.27"
+
+	conflict := apierrors.NewApplyConflict([]metav1.StatusCause{{
+		Type:    metav1.CauseTypeFieldManagerConflict,
+		Message: `conflict with "kubectl-scale"`,
+		Field:   ".spec.replicas",
+	}}, `Apply failed with 1 conflict: conflict with "kubectl-scale": .spec.replicas`)
+
+	var applied []client.Object
+	a := newFakeReconciler(t, interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, p client.Patch, opts ...client.PatchOption) error {
+			patchOpts := (&client.PatchOptions{}).ApplyOptions(opts)
+			if len(patchOpts.DryRun) > 0 {
+				return conflict
+			}
+			if patchOpts.Force != nil && *patchOpts.Force {
+				t.Errorf("Patch() forced the apply under the Preserve policy")
+			}
+			applied = append(applied, obj.DeepCopyObject().(client.Object))
+			return nil
+		},
+	}, myres, live)
+
+	ownerRef := metav1.NewControllerRef(myres, mygroupv1alpha1.GroupVersion.WithKind("MyResource"))
+	report, err := a.applyDeployment(context.Background(), myres, ownerRef, myres.Spec.Image)
+	if err != nil {
+		t.Fatalf("applyDeployment() error = %v", err)
+	}
+	if report.conflict == "" || len(report.conflictFields) != 1 || report.conflictFields[0] != ".spec.replicas" {
+		t.Errorf("applyDeployment() report = %+v", report)
+	}
+	if len(applied) != 1 {
+		t.Fatalf("# of applies should be %d but is %d", 1, len(applied))
+	}
+	u, ok := applied[0].(*unstructured.Unstructured)
+	if !ok {
+		t.Fatalf("applied %T, want an unstructured apply configuration", applied[0])
+	}
+	if u.GroupVersionKind() != (schema.GroupVersionKind{Group: "apps", Version: "v1", Kind: "Deployment"}) {
+		t.Errorf("applied %s", u.GroupVersionKind())
+	}
+	if _, found, _ := unstructured.NestedFieldNoCopy(u.Object, "spec", "replicas"); found {
+		t.Errorf("the apply took over the conflicting .spec.replicas")
+	}
+	containers, _, _ := unstructured.NestedSlice(u.Object, "spec", "template", "spec", "containers")
+	if len(containers) != 1 || containers[0].(map[string]interface{})["image"] != "nginx:1.28" {
+		t.Errorf("the apply did not roll out the new image: %v", containers)
+	}
+
+	a.reportDrift(myres, report)
+	condition := myres.Status.Conditions[0]
+	if condition.Reason != _ownershipConflictReason ||
+		condition.Message != "fields preserved, owned by other managers: .spec.replicas" {
+		t.Errorf("condition = %+v", condition)
+	}
+}
+
+func Test_reconcileCanary_preserve(t *testing.T) {
+	myres := newTestMyResource()
+	myres.Spec.DriftPolicy = mygroupv1alpha1.DriftPolicyPreserve
+	myres.Spec.Canary = &mygroupv1alpha1.CanarySpec{Replicas: 2}
+	primary := ownedDeployment(myres, myres.Name+"-deployment")
+	myres.Spec.Image = "nginx:1.28"
+	ownerRef := metav1.NewControllerRef(myres, mygroupv1alpha1.GroupVersion.WithKind("MyResource"))
+	canary := createCanaryDeployment(myres, ownerRef)
+	canary.Spec.Replicas = ptr.To[int32](5)
+
+	var forced []string
+	a := newFakeReconciler(t, interceptor.Funcs{
+		Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, p client.Patch, opts ...client.PatchOption) error {
+			patchOpts := (&client.PatchOptions{}).ApplyOptions(opts)
+			if len(patchOpts.DryRun) > 0 && obj.GetName() == canary.Name {
+				return apierrors.NewApplyConflict([]metav1.StatusCause{{
+					Type:  metav1.CauseTypeFieldManagerConflict,
+					Field: ".spec.replicas",
+				}}, "Apply failed with 1 conflict")
+			}
+			if patchOpts.Force != nil && *patchOpts.Force {
+				forced = append(forced, obj.GetName())
+			}
+			return nil
+		},
+	}, myres, primary, canary)
+
+	image, _, err := a.reconcileCanary(context.Background(), myres, ownerRef)
+	if err != nil {
+		t.Fatalf("reconcileCanary() error = %v", err)
+	}
+	if image != "nginx:1.27" {
+		t.Errorf("reconcileCanary() image = %q, want the stable image", image)
+	}
+	if len(forced) > 0 {
+		t.Errorf("reconcileCanary() force-applied %v under the Preserve policy", forced)
+	}
+	if myres.Status.Canary == nil || myres.Status.Canary.Phase != mygroupv1alpha1.CanaryPhaseBaking {
+		t.Errorf("canary status = %+v", myres.Status.Canary)
+	}
+}
@@ -0,0 +1,78 @@
+package controller
+
+import (
+	"sync"
+	"time"
+
+	"golang.org/x/time/rate"
+	"k8s.io/client-go/util/workqueue"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+// classRateLimiter backs off each request according to the class of its last
+// error, recorded by handleError right before the request is requeued.
+type classRateLimiter struct {
+	mu       sync.Mutex
+	classes  map[reconcile.Request]errorClass
+	limiters map[errorClass]workqueue.TypedRateLimiter[reconcile.Request]
+}
+
+var _ workqueue.TypedRateLimiter[reconcile.Request] = &classRateLimiter{}
+
+// newClassRateLimiter retries transient errors after 5ms up to 30s, and other
+// errors after 1s up to 5m, both within an overall 10 qps budget.
+func newClassRateLimiter() *classRateLimiter {
+	overall := &workqueue.TypedBucketRateLimiter[reconcile.Request]{
+		Limiter: rate.NewLimiter(rate.Limit(10), 100),
+	}
+	return &classRateLimiter{
+		classes: map[reconcile.Request]errorClass{},
+		limiters: map[errorClass]workqueue.TypedRateLimiter[reconcile.Request]{
+			_transientError: workqueue.NewTypedMaxOfRateLimiter(
+				workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](5*time.Millisecond, 30*time.Second),
+				overall,
+			),
+			_unknownError: workqueue.NewTypedMaxOfRateLimiter(
+				workqueue.NewTypedItemExponentialFailureRateLimiter[reconcile.Request](time.Second, 5*time.Minute),
+				overall,
+			),
+		},
+	}
+}
+
+// observe records the class of the last error of item.
+func (c *classRateLimiter) observe(item reconcile.Request, class errorClass) {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.classes[item] = class
+}
+
+// limiter returns the rate limiter of the last error class of item.
+func (c *classRateLimiter) limiter(item reconcile.Request) workqueue.TypedRateLimiter[reconcile.Request] {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if l, ok := c.limiters[c.classes[item]]; ok {
+		return l
+	}
+	return c.limiters[_unknownError]
+}
+
+func (c *classRateLimiter) When(item reconcile.Request) time.Duration {
+	return c.limiter(item).When(item)
+}
+
+func (c *classRateLimiter) NumRequeues(item reconcile.Request) int {
+	return c.limiter(item).NumRequeues(item)
+}
+
+func (c *classRateLimiter) Forget(item reconcile.Request) {
+	c.mu.Lock()
+	delete(c.classes, item)
+	c.mu.Unlock()
+	for _, l := range c.limiters {
+		l.Forget(item)
+	}
+}
@@ -0,0 +1,411 @@
+package controller
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	appsv1 "k8s.io/api/apps/v1"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"k8s.io/client-go/tools/record"
+	"k8s.io/utils/ptr"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+	"sigs.k8s.io/controller-runtime/pkg/reconcile"
+)
+
+var _testKey = types.NamespacedName{Namespace: "default", Name: "test"}
+
+func newTestMyResource() *mygroupv1alpha1.MyResource {
+	return &mygroupv1alpha1.MyResource{
+		ObjectMeta: metav1.ObjectMeta{
+			Namespace: _testKey.Namespace,
+			Name:      _testKey.Name,
+			UID:       types.UID("uid-test"),
+		},
+		Spec: mygroupv1alpha1.MyResourceSpec{
+			Image:  "nginx:1.27",
+			Memory: resource.MustParse("64Mi"),
+		},
+	}
+}
+
+// newFakeReconciler returns a reconciler backed by a fake client holding objs.
+// The fake client does not support server-side apply, apply patches are
+// turned into a create or an update of the whole object.
+func newFakeReconciler(t *testing.T, funcs interceptor.Funcs, objs ...client.Object) *MyResourceReconciler {
+	t.Helper()
+	scheme := runtime.NewScheme()
+	if err := clientgoscheme.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+	if err := mygroupv1alpha1.AddToScheme(scheme); err != nil {
+		t.Fatal(err)
+	}
+
+	patch := funcs.Patch
+	funcs.Patch = func(ctx context.Context, c client.WithWatch, obj client.Object, p client.Patch, opts ...client.PatchOption) error {
+		if patch != nil {
+			if err := patch(ctx, c, obj, p, opts...); err != nil {
+				return err
+			}
+		}
+		if p.Type() != types.ApplyPatchType {
+			return c.Patch(ctx, obj, p, opts...)
+		}
+		existing := obj.DeepCopyObject().(client.Object)
+		err := c.Get(ctx, client.ObjectKeyFromObject(obj), existing)
+		if apierrors.IsNotFound(err) {
+			return c.Create(ctx, obj)
+		}
+		if err != nil {
+			return err
+		}
+		obj.SetResourceVersion(existing.GetResourceVersion())
+		return c.Update(ctx, obj)
+	}
+
+	return &MyResourceReconciler{
+		Client: fake.NewClientBuilder().
+			WithScheme(scheme).
+			WithObjects(objs...).
+			WithStatusSubresource(&mygroupv1alpha1.MyResource{}, &appsv1.Deployment{}).
+			WithInterceptorFuncs(funcs).
+			Build(),
+		Scheme:      scheme,
+		Recorder:    record.NewFakeRecorder(100),
+		rateLimiter: newClassRateLimiter(),
+	}
+}
+
+// ownedDeployment returns a Deployment controlled by myres and labelled for it.
+func ownedDeployment(myres *mygroupv1alpha1.MyResource, name string) *appsv1.Deployment {
+	deploy := createDeployment(myres,
+		metav1.NewControllerRef(myres, mygroupv1alpha1.GroupVersion.WithKind("MyResource")), myres.Spec.Image)
+	deploy.SetName(name)
+	return deploy
+}
+
+func Test_Reconcile(t *testing.T) {
+	gr := schema.GroupResource{Group: mygroupv1alpha1.GroupVersion.Group, Resource: "myresources"}
+	myresGet := func(err error) interceptor.Funcs {
+		return interceptor.Funcs{
+			Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+				if _, ok := obj.(*mygroupv1alpha1.MyResource); ok {
+					return err
+				}
+				return c.Get(ctx, key, obj, opts...)
+			},
+		}
+	}
+
+	tests := []struct {
+		name         string
+		objs         func() []client.Object
+		funcs        interceptor.Funcs
+		wantErr      bool
+		wantTerminal bool
+		wantRequeue  time.Duration
+		wantBackoff  time.Duration
+		wantState    string
+		wantTrue     []string
+		wantDeploys  []string
+	}{
+		{
+			name: "case 1: not found",
+			objs: func() []client.Object { return nil },
+		},
+		{
+			name:    "case 2: get error",
+			objs:    func() []client.Object { return []client.Object{newTestMyResource()} },
+			funcs:   myresGet(apierrors.NewServiceUnavailable("apiserver is shutting down")),
+			wantErr: true,
+		},
+		{
+			name: "case 3: apply rejected",
+			objs: func() []client.Object { return []client.Object{newTestMyResource()} },
+			funcs: interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, p client.Patch, opts ...client.PatchOption) error {
+					return apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, obj.GetName(), nil)
+				},
+			},
+			wantErr:      true,
+			wantTerminal: true,
+			wantTrue:     []string{_degradedCondition},
+		},
+		{
+			name: "case 4: status update conflict",
+			objs: func() []client.Object { return []client.Object{newTestMyResource()} },
+			funcs: interceptor.Funcs{
+				SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+					return apierrors.NewConflict(gr, obj.GetName(), errors.New("the object has been modified"))
+				},
+			},
+			wantErr:     true,
+			wantDeploys: []string{"test-deployment"},
+		},
+		{
+			name: "case 5: owned duplicate deleted, look-alike reported",
+			objs: func() []client.Object {
+				myres := newTestMyResource()
+				lookalike := createDeployment(myres, &metav1.OwnerReference{}, "busybox")
+				lookalike.SetName("lookalike")
+				lookalike.SetOwnerReferences(nil)
+				return []client.Object{myres, ownedDeployment(myres, "test-old"), lookalike}
+			},
+			wantState:   _buildingState,
+			wantTrue:    []string{_unmanagedDeploymentsCondition},
+			wantDeploys: []string{"lookalike", "test-deployment"},
+		},
+		{
+			name: "case 6: invalid spec",
+			objs: func() []client.Object {
+				myres := newTestMyResource()
+				myres.Spec.Memory = resource.MustParse("-64Mi")
+				return []client.Object{myres}
+			},
+			wantErr:      true,
+			wantTerminal: true,
+			wantTrue:     []string{_degradedCondition},
+		},
+		{
+			name: "case 7: deployment not in the cache yet",
+			objs: func() []client.Object { return []client.Object{newTestMyResource()} },
+			funcs: interceptor.Funcs{
+				Get: func(ctx context.Context, c client.WithWatch, key client.ObjectKey, obj client.Object, opts ...client.GetOption) error {
+					if _, ok := obj.(*appsv1.Deployment); ok {
+						return apierrors.NewNotFound(schema.GroupResource{Group: "apps", Resource: "deployments"}, key.Name)
+					}
+					return c.Get(ctx, key, obj, opts...)
+				},
+				List: func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+					if _, ok := list.(*appsv1.DeploymentList); ok {
+						return nil
+					}
+					return c.List(ctx, list, opts...)
+				},
+			},
+			wantErr:     true,
+			wantBackoff: 5 * time.Millisecond,
+		},
+		{
+			name: "case 8: canary of the previous rollout being deleted",
+			objs: func() []client.Object {
+				myres := newTestMyResource()
+				primary := ownedDeployment(myres, "test-deployment")
+				myres.Spec.Image = "nginx:1.28"
+				myres.Spec.Canary = &mygroupv1alpha1.CanarySpec{Replicas: 1}
+				canary := ownedDeployment(myres, "test-canary")
+				canary.SetLabels(map[string]string{"myresource-canary": myres.GetName()})
+				canary.SetFinalizers([]string{"example.com/wait"})
+				canary.SetDeletionTimestamp(ptr.To(metav1.Now()))
+				return []client.Object{myres, primary, canary}
+			},
+			wantRequeue: _canaryDeletionRequeue,
+			wantDeploys: []string{"test-canary", "test-deployment"},
+		},
+		{
+			name:        "case 9: building",
+			objs:        func() []client.Object { return []client.Object{newTestMyResource()} },
+			wantState:   _buildingState,
+			wantDeploys: []string{"test-deployment"},
+		},
+		{
+			name: "case 10: ready",
+			objs: func() []client.Object {
+				myres := newTestMyResource()
+				deploy := ownedDeployment(myres, "test-deployment")
+				deploy.Status.ReadyReplicas = 1
+				return []client.Object{myres, deploy}
+			},
+			wantState:   _readyState,
+			wantDeploys: []string{"test-deployment"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			r := newFakeReconciler(t, tt.funcs, tt.objs()...)
+			ctx := context.Background()
+
+			req := ctrl.Request{NamespacedName: _testKey}
+			result, err := r.Reconcile(ctx, req)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Reconcile() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if got := errors.Is(err, reconcile.TerminalError(nil)); got != tt.wantTerminal {
+				t.Errorf("Reconcile() terminal = %v, want %v", got, tt.wantTerminal)
+			}
+			if result.RequeueAfter != tt.wantRequeue {
+				t.Errorf("Reconcile() requeueAfter = %v, want %v", result.RequeueAfter, tt.wantRequeue)
+			}
+			if tt.wantBackoff != 0 {
+				if got := r.rateLimiter.When(req); got != tt.wantBackoff {
+					t.Errorf("backoff = %v, want %v", got, tt.wantBackoff)
+				}
+			}
+
+			deployList := appsv1.DeploymentList{}
+			if err := r.Client.List(ctx, &deployList, client.InNamespace(_testKey.Namespace)); err != nil {
+				t.Fatal(err)
+			}
+			var gotDeploys []string
+			for _, deploy := range deployList.Items {
+				gotDeploys = append(gotDeploys, deploy.GetName())
+			}
+			if len(gotDeploys) != len(tt.wantDeploys) {
+				t.Errorf("Reconcile() deployments = %v, want %v", gotDeploys, tt.wantDeploys)
+			}
+			for i := range tt.wantDeploys {
+				if i < len(gotDeploys) && gotDeploys[i] != tt.wantDeploys[i] {
+					t.Errorf("Reconcile() deployments = %v, want %v", gotDeploys, tt.wantDeploys)
+				}
+			}
+
+			myres := &mygroupv1alpha1.MyResource{}
+			if err := r.Client.Get(ctx, _testKey, myres); err != nil {
+				if !apierrors.IsNotFound(err) && tt.funcs.Get == nil {
+					t.Fatal(err)
+				}
+				return
+			}
+			if myres.Status.State != tt.wantState {
+				t.Errorf("Reconcile() state = %q, want %q", myres.Status.State, tt.wantState)
+			}
+			for _, condition := range tt.wantTrue {
+				if !meta.IsStatusConditionTrue(myres.Status.Conditions, condition) {
+					t.Errorf("Reconcile() condition %s is not true: %v", condition, myres.Status.Conditions)
+				}
+			}
+		})
+	}
+}
+
+func Test_Reconcile_buildingToReady(t *testing.T) {
+	r := newFakeReconciler(t, interceptor.Funcs{}, newTestMyResource())
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: _testKey}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	myres := &mygroupv1alpha1.MyResource{}
+	if err := r.Client.Get(ctx, _testKey, myres); err != nil {
+		t.Fatal(err)
+	}
+	if myres.Status.State != _buildingState {
+		t.Fatalf("Reconcile() state = %q, want %q", myres.Status.State, _buildingState)
+	}
+
+	deploy := &appsv1.Deployment{}
+	if err := r.Client.Get(ctx, types.NamespacedName{Namespace: _testKey.Namespace, Name: "test-deployment"}, deploy); err != nil {
+		t.Fatal(err)
+	}
+	if got := deploy.Spec.Template.Spec.Containers[0].Image; got != "nginx:1.27" {
+		t.Errorf("Reconcile() image = %q, want %q", got, "nginx:1.27")
+	}
+	deploy.Status.ReadyReplicas = 1
+	if err := r.Client.Status().Update(ctx, deploy); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+	if err := r.Client.Get(ctx, _testKey, myres); err != nil {
+		t.Fatal(err)
+	}
+	if myres.Status.State != _readyState {
+		t.Errorf("Reconcile() state = %q, want %q", myres.Status.State, _readyState)
+	}
+}
+
+func Test_Reconcile_canaryPromotionStatusConflict(t *testing.T) {
+	myres := newTestMyResource()
+	primary := ownedDeployment(myres, "test-deployment")
+	myres.Spec.Image = "nginx:1.28"
+	myres.Spec.Canary = &mygroupv1alpha1.CanarySpec{
+		Replicas: 1,
+		BakeTime: metav1.Duration{Duration: 5 * time.Minute},
+		Timeout:  metav1.Duration{Duration: 10 * time.Minute},
+	}
+	// started long ago: a canary baking again from this start time would be
+	// rolled back at once
+	myres.Status.Canary = &mygroupv1alpha1.CanaryStatus{
+		Image:     myres.Spec.Image,
+		Phase:     mygroupv1alpha1.CanaryPhaseBaking,
+		StartTime: metav1.NewTime(time.Now().Add(-time.Hour)),
+		ReadyTime: ptr.To(metav1.NewTime(time.Now().Add(-10 * time.Minute))),
+	}
+	canary := createCanaryDeployment(myres,
+		metav1.NewControllerRef(myres, mygroupv1alpha1.GroupVersion.WithKind("MyResource")))
+	canary.Status = appsv1.DeploymentStatus{UpdatedReplicas: 1, ReadyReplicas: 1}
+
+	gr := schema.GroupResource{Group: mygroupv1alpha1.GroupVersion.Group, Resource: "myresources"}
+	conflicted := false
+	r := newFakeReconciler(t, interceptor.Funcs{
+		SubResourceUpdate: func(ctx context.Context, c client.Client, subResource string, obj client.Object, opts ...client.SubResourceUpdateOption) error {
+			// the final status update of the promoting reconcile, the one
+			// with the computed state, conflicts
+			if myres, ok := obj.(*mygroupv1alpha1.MyResource); ok && myres.Status.State != "" && !conflicted {
+				conflicted = true
+				return apierrors.NewConflict(gr, obj.GetName(), errors.New("the object has been modified"))
+			}
+			return c.SubResource(subResource).Update(ctx, obj, opts...)
+		},
+	}, myres, primary, canary)
+	ctx := context.Background()
+	req := ctrl.Request{NamespacedName: _testKey}
+
+	if _, err := r.Reconcile(ctx, req); !apierrors.IsConflict(err) {
+		t.Fatalf("Reconcile() error = %v, want a conflict", err)
+	}
+	if _, err := r.Reconcile(ctx, req); err != nil {
+		t.Fatalf("Reconcile() error = %v", err)
+	}
+
+	got := &mygroupv1alpha1.MyResource{}
+	if err := r.Client.Get(ctx, _testKey, got); err != nil {
+		t.Fatal(err)
+	}
+	if got.Status.Canary == nil || got.Status.Canary.Phase != mygroupv1alpha1.CanaryPhasePromoted {
+		t.Errorf("canary status = %+v, want %s", got.Status.Canary, mygroupv1alpha1.CanaryPhasePromoted)
+	}
+	err := r.Client.Get(ctx, client.ObjectKeyFromObject(canary), &appsv1.Deployment{})
+	if !apierrors.IsNotFound(err) {
+		t.Errorf("canary deployment error = %v, want it deleted and not created again", err)
+	}
+	deploy := &appsv1.Deployment{}
+	if err := r.Client.Get(ctx, client.ObjectKeyFromObject(primary), deploy); err != nil {
+		t.Fatal(err)
+	}
+	if image := deploy.Spec.Template.Spec.Containers[0].Image; image != "nginx:1.28" {
+		t.Errorf("primary image = %q, want the promoted %q", image, "nginx:1.28")
+	}
+
+	events := r.Recorder.(*record.FakeRecorder).Events
+	promoted := 0
+	for len(events) > 0 {
+		event := <-events
+		if strings.Contains(event, _canaryRolledBackReason) {
+			t.Errorf("unexpected event %q", event)
+		}
+		if strings.Contains(event, _canaryPromotedReason) {
+			promoted++
+		}
+	}
+	if promoted != 1 {
+		t.Errorf("# of %s events should be %d but is %d", _canaryPromotedReason, 1, promoted)
+	}
+}
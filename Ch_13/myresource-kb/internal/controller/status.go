@@ -6,13 +6,23 @@ import (
 
 	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
 	appsv1 "k8s.io/api/apps/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/api/meta"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
 	"sigs.k8s.io/controller-runtime/pkg/client"
 	"sigs.k8s.io/controller-runtime/pkg/log"
 )
 
 const (
-	_buildingState = "Building"
-	_readyState    = "Ready"
+	_buildingState  = "Building"
+	_readyState     = "Ready"
+	_stalledState   = "Stalled"
+	_suspendedState = "Suspended"
+
+	_progressingCondition = "Progressing"
+
+	_progressDeadlineExceededReason = "ProgressDeadlineExceeded"
 )
 
 func (a *MyResourceReconciler) computeStatus(
@@ -22,7 +32,9 @@ func (a *MyResourceReconciler) computeStatus(
 
 	logger := log.FromContext(ctx)
 	result := mygroupv1alpha1.MyResourceStatus{
-		State: _buildingState,
+		State:      _buildingState,
+		Conditions: myres.Status.Conditions,
+		Canary:     myres.Status.Canary,
 	}
 
 	deployList := appsv1.DeploymentList{}
@@ -38,23 +50,74 @@ func (a *MyResourceReconciler) computeStatus(
 		return nil, err
 	}
 
-	if len(deployList.Items) == 0 {
-		logger.Info("no deployment found")
-		return &result, nil
+	primary, stale, foreign := partitionDeployments(myres, deployList.Items)
+	reportUnmanaged(myres, &result, foreign)
+	if err := a.deleteStaleDeployments(ctx, myres, stale); err != nil {
+		return nil, err
 	}
 
-	if len(deployList.Items) > 1 {
-		logger.Info("too many deployments found", "count",
-			len(deployList.Items))
-		return nil, fmt.Errorf("%d deployment found, expected 1",
-			len(deployList.Items))
+	if primary == nil {
+		// the Deployment has just been applied, it is missing from the list
+		// when the cache has not seen it yet or when its labels were changed
+		primary = &appsv1.Deployment{}
+		key := types.NamespacedName{Namespace: myres.GetNamespace(), Name: myres.GetName() + "-deployment"}
+		err := a.Client.Get(ctx, key, primary)
+		if errors.IsNotFound(err) {
+			return nil, transientError(fmt.Errorf("deployment %s not in the cache yet", key.Name))
+		}
+		if err != nil {
+			return nil, err
+		}
+		if !metav1.IsControlledBy(primary, myres) {
+			logger.Info("no deployment found")
+			meta.RemoveStatusCondition(&result.Conditions, _podsHealthyCondition)
+			return &result, nil
+		}
+	}
+
+	pods, err := a.currentPods(ctx, primary)
+	if err != nil {
+		return nil, err
 	}
+	setPodHealth(myres, &result, podIssues(pods))
 
-	status := deployList.Items[0].Status
+	status := primary.Status
 	logger.Info("got deployment status", "status", status)
 	if status.ReadyReplicas == 1 {
 		result.State = _readyState
 	}
+	if myres.Spec.Suspend {
+		result.State = _suspendedState
+	}
+
+	if progressing := progressingCondition(primary, myres.GetGeneration()); progressing != nil {
+		if progressing.Reason == _progressDeadlineExceededReason {
+			result.State = _stalledState
+		}
+		meta.SetStatusCondition(&result.Conditions, *progressing)
+	}
 
 	return &result, nil
 }
+
+// progressingCondition mirrors the Progressing condition of the Deployment, so a
+// rollout that exceeded its progress deadline is surfaced on the MyResource.
+// It returns nil while the Deployment controller has not reported progress yet.
+func progressingCondition(
+	deploy *appsv1.Deployment,
+	generation int64,
+) *metav1.Condition {
+	for _, c := range deploy.Status.Conditions {
+		if c.Type != appsv1.DeploymentProgressing {
+			continue
+		}
+		return &metav1.Condition{
+			Type:               _progressingCondition,
+			Status:             metav1.ConditionStatus(c.Status),
+			ObservedGeneration: generation,
+			Reason:             c.Reason,
+			Message:            c.Message,
+		}
+	}
+	return nil
+}
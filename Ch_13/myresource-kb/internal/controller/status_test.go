@@ -0,0 +1,76 @@
+package controller
+
+import (
+	"reflect"
+	"testing"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+)
+
+func Test_progressingCondition(t *testing.T) {
+	tests := []struct {
+		name       string
+		conditions []appsv1.DeploymentCondition
+		want       *metav1.Condition
+	}{
+		{
+			name: "case 1: no progress reported yet",
+			want: nil,
+		},
+		{
+			name: "case 2: rollout in progress",
+			conditions: []appsv1.DeploymentCondition{
+				{
+					Type:   appsv1.DeploymentAvailable,
+					Status: corev1.ConditionFalse,
+					Reason: "MinimumReplicasUnavailable",
+				},
+				{
+					Type:    appsv1.DeploymentProgressing,
+					Status:  corev1.ConditionTrue,
+					Reason:  "ReplicaSetUpdated",
+					Message: `ReplicaSet "myres-deployment-5d4f" is progressing.`,
+				},
+			},
+			want: &metav1.Condition{
+				Type:               _progressingCondition,
+				Status:             metav1.ConditionTrue,
+				ObservedGeneration: 2,
+				Reason:             "ReplicaSetUpdated",
+				Message:            `ReplicaSet "myres-deployment-5d4f" is progressing.`,
+			},
+		},
+		{
+			name: "case 3: progress deadline exceeded",
+			conditions: []appsv1.DeploymentCondition{
+				{
+					Type:    appsv1.DeploymentProgressing,
+					Status:  corev1.ConditionFalse,
+					Reason:  _progressDeadlineExceededReason,
+					Message: `ReplicaSet "myres-deployment-5d4f" has timed out progressing.`,
+				},
+			},
+			want: &metav1.Condition{
+				Type:               _progressingCondition,
+				Status:             metav1.ConditionFalse,
+				ObservedGeneration: 2,
+				Reason:             _progressDeadlineExceededReason,
+				Message:            `ReplicaSet "myres-deployment-5d4f" has timed out progressing.`,
+			},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			deploy := &appsv1.Deployment{
+				Status: appsv1.DeploymentStatus{
+					Conditions: tt.conditions,
+				},
+			}
+			if got := progressingCondition(deploy, 2); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("progressingCondition() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,44 @@
+package controller
+
+import (
+	"context"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/trace"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+)
+
+const (
+	_tracerName = "github.com/myid/myresource/internal/controller"
+
+	_attrName       = "myresource.name"
+	_attrNamespace  = "myresource.namespace"
+	_attrGeneration = "myresource.generation"
+)
+
+// tracer returns the tracer of the reconciler, taken from the global provider
+// unless a TracerProvider is set.
+func (a *MyResourceReconciler) tracer() trace.Tracer {
+	if a.TracerProvider != nil {
+		return a.TracerProvider.Tracer(_tracerName)
+	}
+	return otel.Tracer(_tracerName)
+}
+
+// endSpan ends span, marking it as failed when err is set.
+func endSpan(span trace.Span, err error) {
+	if err != nil {
+		span.RecordError(err)
+		span.SetStatus(codes.Error, err.Error())
+	}
+	span.End()
+}
+
+// updateStatus updates the status of myres within its own span.
+func (a *MyResourceReconciler) updateStatus(ctx context.Context, myres client.Object) error {
+	ctx, span := a.tracer().Start(ctx, "updateStatus")
+	err := a.Client.Status().Update(ctx, myres)
+	endSpan(span, err)
+	return err
+}
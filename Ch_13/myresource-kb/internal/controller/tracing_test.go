@@ -0,0 +1,97 @@
+package controller
+
+import (
+	"context"
+	"testing"
+
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	apierrors "k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	ctrl "sigs.k8s.io/controller-runtime"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+)
+
+func Test_Reconcile_tracing(t *testing.T) {
+	tests := []struct {
+		name      string
+		objs      func() []client.Object
+		funcs     interceptor.Funcs
+		wantSpans []string
+		wantError string
+	}{
+		{
+			name:      "case 1: not found",
+			objs:      func() []client.Object { return nil },
+			wantSpans: []string{"Reconcile"},
+		},
+		{
+			name:      "case 2: reconciled",
+			objs:      func() []client.Object { return []client.Object{newTestMyResource()} },
+			wantSpans: []string{"applyDeployment", "computeStatus", "updateStatus", "Reconcile"},
+		},
+		{
+			name: "case 3: apply rejected",
+			objs: func() []client.Object { return []client.Object{newTestMyResource()} },
+			funcs: interceptor.Funcs{
+				Patch: func(ctx context.Context, c client.WithWatch, obj client.Object, p client.Patch, opts ...client.PatchOption) error {
+					return apierrors.NewInvalid(schema.GroupKind{Group: "apps", Kind: "Deployment"}, obj.GetName(), nil)
+				},
+			},
+			// handleError writes the Degraded condition without a span of its own
+			wantSpans: []string{"applyDeployment", "Reconcile"},
+			wantError: "applyDeployment",
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			recorder := tracetest.NewSpanRecorder()
+			r := newFakeReconciler(t, tt.funcs, tt.objs()...)
+			r.TracerProvider = sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+			_, _ = r.Reconcile(context.Background(), ctrl.Request{NamespacedName: _testKey})
+
+			spans := recorder.Ended()
+			var got []string
+			for _, span := range spans {
+				got = append(got, span.Name())
+			}
+			if len(got) != len(tt.wantSpans) {
+				t.Fatalf("Reconcile() spans = %v, want %v", got, tt.wantSpans)
+			}
+			for i := range tt.wantSpans {
+				if got[i] != tt.wantSpans[i] {
+					t.Fatalf("Reconcile() spans = %v, want %v", got, tt.wantSpans)
+				}
+			}
+
+			root := spans[len(spans)-1]
+			attrs := attribute.NewSet(root.Attributes()...)
+			if v, _ := attrs.Value(_attrName); v.AsString() != _testKey.Name {
+				t.Errorf("Reconcile() %s = %q, want %q", _attrName, v.AsString(), _testKey.Name)
+			}
+			if v, _ := attrs.Value(_attrNamespace); v.AsString() != _testKey.Namespace {
+				t.Errorf("Reconcile() %s = %q, want %q", _attrNamespace, v.AsString(), _testKey.Namespace)
+			}
+			if _, ok := attrs.Value(_attrGeneration); ok != (len(spans) > 1) {
+				t.Errorf("Reconcile() %s set = %v, want %v", _attrGeneration, ok, len(spans) > 1)
+			}
+
+			for _, span := range spans[:len(spans)-1] {
+				if span.Parent().SpanID() != root.SpanContext().SpanID() {
+					t.Errorf("Reconcile() span %s is not a child of Reconcile", span.Name())
+				}
+				failed := span.Status().Code == codes.Error
+				if failed != (span.Name() == tt.wantError) {
+					t.Errorf("Reconcile() span %s failed = %v, want %v", span.Name(), failed, !failed)
+				}
+			}
+			if failed := root.Status().Code == codes.Error; failed != (tt.wantError != "") {
+				t.Errorf("Reconcile() span failed = %v, want %v", failed, tt.wantError != "")
+			}
+		})
+	}
+}
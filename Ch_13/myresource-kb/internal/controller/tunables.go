@@ -0,0 +1,102 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// Tunables holds the controller settings that can change while the manager
+// runs. The controller is started with limit workers and Tunables caps how
+// many of them reconcile at once. A nil *Tunables imposes no limit.
+type Tunables struct {
+	limit int
+
+	mu              sync.Mutex
+	maxConcurrent   int
+	running         int
+	requeueInterval time.Duration
+	// changed is closed and replaced whenever a slot is released or the
+	// maximum grows, waking the waiting workers
+	changed chan struct{}
+}
+
+// NewTunables returns Tunables allowing at most limit concurrent reconciles.
+func NewTunables(limit int) *Tunables {
+	return &Tunables{
+		limit:         limit,
+		maxConcurrent: limit,
+		changed:       make(chan struct{}),
+	}
+}
+
+// Limit is the number of workers the controller has to be started with.
+func (t *Tunables) Limit() int {
+	return t.limit
+}
+
+// Set changes the maximum number of concurrent reconciles, clamped to
+// [1, limit], and the interval after which a MyResource is reconciled again.
+func (t *Tunables) Set(maxConcurrent int, requeueInterval time.Duration) {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.maxConcurrent = max(1, min(maxConcurrent, t.limit))
+	t.requeueInterval = requeueInterval
+	t.notify()
+}
+
+// RequeueInterval returns the interval after which a MyResource is
+// reconciled again, 0 when it is only reconciled on changes.
+func (t *Tunables) RequeueInterval() time.Duration {
+	if t == nil {
+		return 0
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return t.requeueInterval
+}
+
+// acquire blocks until the reconcile may run and returns the function
+// releasing its slot.
+func (t *Tunables) acquire(ctx context.Context) (func(), error) {
+	if t == nil {
+		return func() {}, nil
+	}
+	for {
+		t.mu.Lock()
+		if t.running < t.maxConcurrent {
+			t.running++
+			t.mu.Unlock()
+			return t.release, nil
+		}
+		changed := t.changed
+		t.mu.Unlock()
+
+		select {
+		case <-changed:
+		case <-ctx.Done():
+			return nil, ctx.Err()
+		}
+	}
+}
+
+func (t *Tunables) release() {
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.running--
+	t.notify()
+}
+
+// notify wakes the waiting workers, t.mu must be held.
+func (t *Tunables) notify() {
+	close(t.changed)
+	t.changed = make(chan struct{})
+}
+
+// requeueAfter returns the shortest non-zero of the two durations.
+func requeueAfter(a, b time.Duration) time.Duration {
+	if a == 0 || (b != 0 && b < a) {
+		return b
+	}
+	return a
+}
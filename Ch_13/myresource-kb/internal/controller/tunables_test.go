@@ -0,0 +1,104 @@
+package controller
+
+import (
+	"context"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+)
+
+func Test_Tunables_acquire(t *testing.T) {
+	tests := []struct {
+		name          string
+		limit         int
+		maxConcurrent int
+		want          int32
+	}{
+		{name: "case 1: one at a time", limit: 4, maxConcurrent: 1, want: 1},
+		{name: "case 2: below the limit", limit: 4, maxConcurrent: 2, want: 2},
+		{name: "case 3: clamped to the limit", limit: 3, maxConcurrent: 10, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tunables := NewTunables(tt.limit)
+			tunables.Set(tt.maxConcurrent, 0)
+
+			var running, peak atomic.Int32
+			var wg sync.WaitGroup
+			for range 8 {
+				wg.Add(1)
+				go func() {
+					defer wg.Done()
+					release, err := tunables.acquire(context.Background())
+					if err != nil {
+						t.Error(err)
+						return
+					}
+					defer release()
+					n := running.Add(1)
+					for {
+						p := peak.Load()
+						if n <= p || peak.CompareAndSwap(p, n) {
+							break
+						}
+					}
+					time.Sleep(10 * time.Millisecond)
+					running.Add(-1)
+				}()
+			}
+			wg.Wait()
+			if got := peak.Load(); got != tt.want {
+				t.Errorf("acquire() peak concurrency = %d, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_Tunables_grow(t *testing.T) {
+	tunables := NewTunables(2)
+	tunables.Set(1, 0)
+	release, _ := tunables.acquire(context.Background())
+	defer release()
+
+	acquired := make(chan struct{})
+	go func() {
+		release, err := tunables.acquire(context.Background())
+		if err == nil {
+			release()
+		}
+		close(acquired)
+	}()
+	select {
+	case <-acquired:
+		t.Fatal("acquire() did not wait for a free slot")
+	case <-time.After(20 * time.Millisecond):
+	}
+
+	tunables.Set(2, 0)
+	select {
+	case <-acquired:
+	case <-time.After(time.Second):
+		t.Fatal("acquire() did not proceed after the maximum grew")
+	}
+}
+
+func Test_requeueAfter(t *testing.T) {
+	tests := []struct {
+		name string
+		a, b time.Duration
+		want time.Duration
+	}{
+		{name: "case 1: both unset", want: 0},
+		{name: "case 2: only canary", a: time.Minute, want: time.Minute},
+		{name: "case 3: only interval", b: 5 * time.Minute, want: 5 * time.Minute},
+		{name: "case 4: shortest", a: 10 * time.Minute, b: 5 * time.Minute, want: 5 * time.Minute},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := requeueAfter(tt.a, tt.b); got != tt.want {
+				t.Errorf("requeueAfter() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,308 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package migration migrates the stored objects of a CRD to its storage
+// version. When the storage version changes, objects stay stored in the
+// previous one until they are written again, and status.storedVersions keeps
+// listing it, so the version cannot be removed from the CRD. The migrator
+// writes every object back unchanged, a page at a time, which makes the API
+// server store it in the storage version, then prunes storedVersions. Its
+// progress is kept in a ConfigMap, so an interrupted migration resumes from the
+// last page done, as long as the CRD has not changed since it started. The
+// ConfigMap is deleted once storedVersions is pruned.
+package migration
+
+import (
+	"context"
+	"fmt"
+	"slices"
+	"strconv"
+	"strings"
+	"time"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/client-go/util/retry"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// Keys of the checkpoint ConfigMap.
+const (
+	// StorageVersionKey is the storage version the objects are migrated to, a
+	// checkpoint of another version is started over.
+	StorageVersionKey = "storageVersion"
+	// StoredVersionsKey is the comma-separated storedVersions of the CRD when
+	// the migration started, a checkpoint of other storedVersions is started
+	// over.
+	StoredVersionsKey = "storedVersions"
+	// GenerationKey is the generation of the CRD when the migration started:
+	// the storage version may have been changed back and forth since, a
+	// checkpoint of another generation is started over.
+	GenerationKey = "crdGeneration"
+	// ContinueKey is the continue token of the next page to migrate.
+	ContinueKey = "continue"
+	// MigratedKey is the number of objects written back so far, the ones
+	// written or deleted by someone else meanwhile are not counted.
+	MigratedKey = "migrated"
+	// CompletedKey is "true" once every object has been migrated.
+	CompletedKey = "completed"
+)
+
+// DefaultPageSize is the number of objects listed at once when
+// Migrator.PageSize is not set.
+const DefaultPageSize = 100
+
+// DefaultRetryInterval is how long Start waits before retrying a failed
+// migration when Migrator.RetryInterval is not set.
+const DefaultRetryInterval = time.Minute
+
+// Migrator migrates the objects of a CRD to its storage version. It implements
+// manager.Runnable, Migrate can also be called on its own.
+type Migrator struct {
+	// Client writes the objects, the checkpoint and the status of the CRD.
+	Client client.Client
+	// Reader reads the CRD and the checkpoint and lists the objects, it should
+	// not be backed by the cache, which does not page lists.
+	Reader client.Reader
+	// CRD is the name of the CRD whose objects are migrated.
+	CRD string
+	// Checkpoint is the ConfigMap keeping the progress of the migration.
+	Checkpoint types.NamespacedName
+	// PageSize is the number of objects listed, and migrated, at once.
+	PageSize int64
+	// RetryInterval is how long Start waits before retrying a failed migration,
+	// DefaultRetryInterval when not set.
+	RetryInterval time.Duration
+}
+
+// Start migrates the objects, retrying every RetryInterval on failure, until
+// the migration completes or ctx is done.
+func (m *Migrator) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("migration")
+
+	retryInterval := m.RetryInterval
+	if retryInterval <= 0 {
+		retryInterval = DefaultRetryInterval
+	}
+	for {
+		err := m.Migrate(ctx)
+		if err == nil {
+			return nil
+		}
+		logger.Error(err, "unable to migrate stored objects", "crd", m.CRD)
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-time.After(retryInterval):
+		}
+	}
+}
+
+// NeedLeaderElection implements manager.LeaderElectionRunnable, a single
+// replica migrates the objects.
+func (m *Migrator) NeedLeaderElection() bool {
+	return true
+}
+
+// Migrate writes every object of the CRD back in its storage version, resuming
+// from the checkpoint, and then removes the other versions from its
+// storedVersions. It does nothing when storedVersions only lists the storage
+// version, but deleting the checkpoint left by a previous migration.
+func (m *Migrator) Migrate(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("migration")
+
+	crd := &apiextensionsv1.CustomResourceDefinition{}
+	if err := m.Reader.Get(ctx, types.NamespacedName{Name: m.CRD}, crd); err != nil {
+		return fmt.Errorf("getting CRD %s: %w", m.CRD, err)
+	}
+	storageVersion := storageVersionOf(crd)
+	if storageVersion == "" {
+		return fmt.Errorf("CRD %s has no storage version", m.CRD)
+	}
+	if slices.Equal(crd.Status.StoredVersions, []string{storageVersion}) {
+		logger.V(1).Info("stored objects already in the storage version", "crd", m.CRD, "version", storageVersion)
+		return m.deleteCheckpoint(ctx)
+	}
+
+	checkpoint, err := m.loadCheckpoint(ctx, crd)
+	if err != nil {
+		return err
+	}
+	if checkpoint.Data[CompletedKey] != "true" {
+		logger.Info("migrating stored objects", "crd", m.CRD, "version", storageVersion,
+			"storedVersions", crd.Status.StoredVersions, "resumed", checkpoint.Data[ContinueKey] != "")
+		if err := m.migrateObjects(ctx, crd, checkpoint); err != nil {
+			return err
+		}
+	}
+	if err := m.pruneStoredVersions(ctx, storageVersion); err != nil {
+		return err
+	}
+	return m.deleteCheckpoint(ctx)
+}
+
+// migrateObjects writes the objects back a page at a time, saving the
+// checkpoint after each page.
+func (m *Migrator) migrateObjects(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition, checkpoint *corev1.ConfigMap) error {
+	logger := log.FromContext(ctx).WithName("migration")
+
+	pageSize := m.PageSize
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	migrated, _ := strconv.ParseInt(checkpoint.Data[MigratedKey], 10, 64)
+	continueToken := checkpoint.Data[ContinueKey]
+	for {
+		list := &unstructured.UnstructuredList{}
+		list.SetGroupVersionKind(schema.GroupVersionKind{
+			Group:   crd.Spec.Group,
+			Version: storageVersionOf(crd),
+			Kind:    crd.Spec.Names.ListKind,
+		})
+		err := m.Reader.List(ctx, list, client.Limit(pageSize), client.Continue(continueToken))
+		if errors.IsResourceExpired(err) && continueToken != "" {
+			// the token outlived the compaction of etcd, the objects already
+			// migrated are rewritten as well
+			logger.Info("continue token expired, starting over", "crd", m.CRD)
+			continueToken = ""
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("listing %s: %w", crd.Spec.Names.Plural, err)
+		}
+
+		for i := range list.Items {
+			obj := &list.Items[i]
+			// the update carries the listed resourceVersion: a conflict or a
+			// deletion means the object has been written since, in the
+			// storage version
+			err := m.Client.Update(ctx, obj)
+			if errors.IsConflict(err) || errors.IsNotFound(err) {
+				continue
+			}
+			if err != nil {
+				return fmt.Errorf("migrating %s %s: %w", crd.Spec.Names.Kind, client.ObjectKeyFromObject(obj), err)
+			}
+			migrated++
+		}
+
+		continueToken = list.GetContinue()
+		checkpoint.Data[ContinueKey] = continueToken
+		checkpoint.Data[MigratedKey] = strconv.FormatInt(migrated, 10)
+		if continueToken == "" {
+			checkpoint.Data[CompletedKey] = "true"
+		}
+		if err := m.saveCheckpoint(ctx, checkpoint); err != nil {
+			return err
+		}
+		logger.V(1).Info("page migrated", "crd", m.CRD, "migrated", migrated)
+		if continueToken == "" {
+			logger.Info("stored objects migrated", "crd", m.CRD, "migrated", migrated)
+			return nil
+		}
+	}
+}
+
+// pruneStoredVersions leaves only the storage version in the storedVersions of
+// the CRD.
+func (m *Migrator) pruneStoredVersions(ctx context.Context, storageVersion string) error {
+	logger := log.FromContext(ctx).WithName("migration")
+
+	return retry.RetryOnConflict(retry.DefaultRetry, func() error {
+		crd := &apiextensionsv1.CustomResourceDefinition{}
+		if err := m.Reader.Get(ctx, types.NamespacedName{Name: m.CRD}, crd); err != nil {
+			return fmt.Errorf("getting CRD %s: %w", m.CRD, err)
+		}
+		if storageVersionOf(crd) != storageVersion {
+			return fmt.Errorf("the storage version of CRD %s changed from %s to %s during the migration",
+				m.CRD, storageVersion, storageVersionOf(crd))
+		}
+		if slices.Equal(crd.Status.StoredVersions, []string{storageVersion}) {
+			return nil
+		}
+		logger.Info("pruning stored versions", "crd", m.CRD, "storedVersions", crd.Status.StoredVersions,
+			"version", storageVersion)
+		crd.Status.StoredVersions = []string{storageVersion}
+		return m.Client.Status().Update(ctx, crd)
+	})
+}
+
+// loadCheckpoint returns the checkpoint of the migration of crd, a new one
+// when there is none or when it started from another storage version,
+// storedVersions or generation of crd.
+func (m *Migrator) loadCheckpoint(ctx context.Context, crd *apiextensionsv1.CustomResourceDefinition) (*corev1.ConfigMap, error) {
+	checkpoint := &corev1.ConfigMap{}
+	err := m.Reader.Get(ctx, m.Checkpoint, checkpoint)
+	if err != nil && !errors.IsNotFound(err) {
+		return nil, fmt.Errorf("getting checkpoint %s: %w", m.Checkpoint, err)
+	}
+	if errors.IsNotFound(err) {
+		checkpoint.Name = m.Checkpoint.Name
+		checkpoint.Namespace = m.Checkpoint.Namespace
+	}
+	start := map[string]string{
+		StorageVersionKey: storageVersionOf(crd),
+		StoredVersionsKey: strings.Join(crd.Status.StoredVersions, ","),
+		GenerationKey:     strconv.FormatInt(crd.Generation, 10),
+	}
+	for key, value := range start {
+		if checkpoint.Data[key] != value {
+			checkpoint.Data = start
+			break
+		}
+	}
+	return checkpoint, nil
+}
+
+// deleteCheckpoint deletes the checkpoint, if any.
+func (m *Migrator) deleteCheckpoint(ctx context.Context) error {
+	checkpoint := &corev1.ConfigMap{}
+	checkpoint.Name = m.Checkpoint.Name
+	checkpoint.Namespace = m.Checkpoint.Namespace
+	if err := m.Client.Delete(ctx, checkpoint); err != nil && !errors.IsNotFound(err) {
+		return fmt.Errorf("deleting checkpoint %s: %w", m.Checkpoint, err)
+	}
+	return nil
+}
+
+// saveCheckpoint creates or updates the checkpoint.
+func (m *Migrator) saveCheckpoint(ctx context.Context, checkpoint *corev1.ConfigMap) error {
+	var err error
+	if checkpoint.ResourceVersion == "" {
+		err = m.Client.Create(ctx, checkpoint)
+	} else {
+		err = m.Client.Update(ctx, checkpoint)
+	}
+	if err != nil {
+		return fmt.Errorf("saving checkpoint %s: %w", m.Checkpoint, err)
+	}
+	return nil
+}
+
+// storageVersionOf returns the storage version of crd.
+func storageVersionOf(crd *apiextensionsv1.CustomResourceDefinition) string {
+	for _, version := range crd.Spec.Versions {
+		if version.Storage {
+			return version.Name
+		}
+	}
+	return ""
+}
@@ -0,0 +1,292 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migration
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"slices"
+	"sort"
+	"strconv"
+	"testing"
+
+	corev1 "k8s.io/api/core/v1"
+	apiextensionsv1 "k8s.io/apiextensions-apiserver/pkg/apis/apiextensions/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/runtime/schema"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/client/fake"
+	"sigs.k8s.io/controller-runtime/pkg/client/interceptor"
+
+	mygroupv1beta1 "github.com/myid/myresource/api/v1beta1"
+)
+
+const testCRD = "myresources.mygroup.myid.dev"
+
+var testCheckpoint = types.NamespacedName{Namespace: "system", Name: "storage-migration"}
+
+func newCRD(storedVersions ...string) *apiextensionsv1.CustomResourceDefinition {
+	return &apiextensionsv1.CustomResourceDefinition{
+		ObjectMeta: metav1.ObjectMeta{Name: testCRD, Generation: 2},
+		Spec: apiextensionsv1.CustomResourceDefinitionSpec{
+			Group: "mygroup.myid.dev",
+			Names: apiextensionsv1.CustomResourceDefinitionNames{
+				Plural:   "myresources",
+				Kind:     "MyResource",
+				ListKind: "MyResourceList",
+			},
+			Versions: []apiextensionsv1.CustomResourceDefinitionVersion{
+				{Name: "v1alpha1", Served: true},
+				{Name: "v1beta1", Served: true, Storage: true},
+			},
+		},
+		Status: apiextensionsv1.CustomResourceDefinitionStatus{StoredVersions: storedVersions},
+	}
+}
+
+// started returns data completed with the keys of a migration started from the
+// CRD of newCRD("v1alpha1", "v1beta1").
+func started(data map[string]string) map[string]string {
+	started := map[string]string{StorageVersionKey: "v1beta1", StoredVersionsKey: "v1alpha1,v1beta1", GenerationKey: "2"}
+	for key, value := range data {
+		started[key] = value
+	}
+	return started
+}
+
+func newCheckpoint(data map[string]string) *corev1.ConfigMap {
+	return &corev1.ConfigMap{
+		ObjectMeta: metav1.ObjectMeta{Namespace: testCheckpoint.Namespace, Name: testCheckpoint.Name},
+		Data:       data,
+	}
+}
+
+// pagedList pages the lists of MyResources as the API server does, the
+// continue token being the index of the next object. The fake client ignores
+// the limit and continue options.
+func pagedList(expiredToken string) func(context.Context, client.WithWatch, client.ObjectList, ...client.ListOption) error {
+	return func(ctx context.Context, c client.WithWatch, list client.ObjectList, opts ...client.ListOption) error {
+		ulist, ok := list.(*unstructured.UnstructuredList)
+		if !ok {
+			return c.List(ctx, list, opts...)
+		}
+		listOpts := (&client.ListOptions{}).ApplyOptions(opts)
+		if listOpts.Continue != "" && listOpts.Continue == expiredToken {
+			return errors.NewResourceExpired("continue token expired")
+		}
+		if err := c.List(ctx, ulist, opts...); err != nil {
+			return err
+		}
+		sort.Slice(ulist.Items, func(i, j int) bool {
+			return ulist.Items[i].GetName() < ulist.Items[j].GetName()
+		})
+		start, _ := strconv.Atoi(listOpts.Continue)
+		end := min(start+int(listOpts.Limit), len(ulist.Items))
+		if end < len(ulist.Items) {
+			ulist.SetContinue(strconv.Itoa(end))
+		}
+		ulist.Items = ulist.Items[start:end]
+		return nil
+	}
+}
+
+func Test_Migrator_Migrate(t *testing.T) {
+	scheme := runtime.NewScheme()
+	for _, addToScheme := range []func(*runtime.Scheme) error{
+		clientgoscheme.AddToScheme,
+		apiextensionsv1.AddToScheme,
+		mygroupv1beta1.AddToScheme,
+	} {
+		if err := addToScheme(scheme); err != nil {
+			t.Fatal(err)
+		}
+	}
+
+	tests := []struct {
+		name               string
+		crd                *apiextensionsv1.CustomResourceDefinition
+		checkpoint         *corev1.ConfigMap
+		expiredToken       string
+		failUpdate         string
+		conflictUpdate     string
+		wantMigrated       []string
+		wantErr            bool
+		wantStoredVersions []string
+		wantCheckpoint     map[string]string
+	}{
+		{
+			name:               "case 1: all the objects are migrated, storedVersions is pruned and the checkpoint deleted",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			wantMigrated:       []string{"myres-0", "myres-1", "myres-2", "myres-3", "myres-4"},
+			wantStoredVersions: []string{"v1beta1"},
+		},
+		{
+			name:               "case 2: nothing to migrate, the checkpoint left is deleted",
+			crd:                newCRD("v1beta1"),
+			checkpoint:         newCheckpoint(started(map[string]string{MigratedKey: "5", CompletedKey: "true"})),
+			wantStoredVersions: []string{"v1beta1"},
+		},
+		{
+			name:               "case 3: resumed from the checkpoint",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			checkpoint:         newCheckpoint(started(map[string]string{ContinueKey: "4", MigratedKey: "4"})),
+			wantMigrated:       []string{"myres-4"},
+			wantStoredVersions: []string{"v1beta1"},
+		},
+		{
+			name:               "case 4: completed checkpoint, only storedVersions is pruned",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			checkpoint:         newCheckpoint(started(map[string]string{MigratedKey: "5", CompletedKey: "true"})),
+			wantStoredVersions: []string{"v1beta1"},
+		},
+		{
+			name:               "case 5: checkpoint of another storage version, started over",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			checkpoint:         newCheckpoint(started(map[string]string{StorageVersionKey: "v1alpha1", MigratedKey: "5", CompletedKey: "true"})),
+			wantMigrated:       []string{"myres-0", "myres-1", "myres-2", "myres-3", "myres-4"},
+			wantStoredVersions: []string{"v1beta1"},
+		},
+		{
+			name:               "case 6: expired continue token, started over",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			checkpoint:         newCheckpoint(started(map[string]string{ContinueKey: "expired", MigratedKey: "2"})),
+			expiredToken:       "expired",
+			wantMigrated:       []string{"myres-0", "myres-1", "myres-2", "myres-3", "myres-4"},
+			wantStoredVersions: []string{"v1beta1"},
+		},
+		{
+			name:               "case 7: failed update, the checkpoint keeps the pages done",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			failUpdate:         "myres-3",
+			wantMigrated:       []string{"myres-0", "myres-1", "myres-2"},
+			wantErr:            true,
+			wantStoredVersions: []string{"v1alpha1", "v1beta1"},
+			wantCheckpoint:     started(map[string]string{ContinueKey: "2", MigratedKey: "2"}),
+		},
+		{
+			name:               "case 8: checkpoint of other storedVersions, started over",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			checkpoint:         newCheckpoint(started(map[string]string{StoredVersionsKey: "v1beta1", MigratedKey: "5", CompletedKey: "true"})),
+			failUpdate:         "myres-3",
+			wantMigrated:       []string{"myres-0", "myres-1", "myres-2"},
+			wantErr:            true,
+			wantStoredVersions: []string{"v1alpha1", "v1beta1"},
+			wantCheckpoint:     started(map[string]string{ContinueKey: "2", MigratedKey: "2"}),
+		},
+		{
+			name: "case 9: checkpoint of another generation of the CRD, started over",
+			crd:  newCRD("v1alpha1", "v1beta1"),
+			// the storage version went back to v1alpha1 and forth to v1beta1
+			// since, storedVersions is the same
+			checkpoint:         newCheckpoint(started(map[string]string{GenerationKey: "1", ContinueKey: "4", MigratedKey: "4"})),
+			wantMigrated:       []string{"myres-0", "myres-1", "myres-2", "myres-3", "myres-4"},
+			wantStoredVersions: []string{"v1beta1"},
+		},
+		{
+			name:               "case 10: objects written meanwhile are not counted",
+			crd:                newCRD("v1alpha1", "v1beta1"),
+			conflictUpdate:     "myres-1",
+			failUpdate:         "myres-3",
+			wantMigrated:       []string{"myres-0", "myres-2"},
+			wantErr:            true,
+			wantStoredVersions: []string{"v1alpha1", "v1beta1"},
+			wantCheckpoint:     started(map[string]string{ContinueKey: "2", MigratedKey: "1"}),
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			objs := []client.Object{tt.crd}
+			if tt.checkpoint != nil {
+				objs = append(objs, tt.checkpoint)
+			}
+			for i := 0; i < 5; i++ {
+				// the fake client does not convert, the objects are listed in
+				// the version they are created in
+				objs = append(objs, &mygroupv1beta1.MyResource{
+					ObjectMeta: metav1.ObjectMeta{Namespace: fmt.Sprintf("ns-%d", i%2), Name: fmt.Sprintf("myres-%d", i)},
+				})
+			}
+			var migrated []string
+			c := fake.NewClientBuilder().
+				WithScheme(scheme).
+				WithObjects(objs...).
+				WithStatusSubresource(&apiextensionsv1.CustomResourceDefinition{}).
+				WithInterceptorFuncs(interceptor.Funcs{
+					List: pagedList(tt.expiredToken),
+					Update: func(ctx context.Context, c client.WithWatch, obj client.Object, opts ...client.UpdateOption) error {
+						if _, ok := obj.(*unstructured.Unstructured); ok {
+							if obj.GetName() == tt.failUpdate {
+								return errors.NewInternalError(fmt.Errorf("etcd unavailable"))
+							}
+							if obj.GetName() == tt.conflictUpdate {
+								return errors.NewConflict(schema.GroupResource{Group: "mygroup.myid.dev", Resource: "myresources"},
+									obj.GetName(), fmt.Errorf("the object has been modified"))
+							}
+							migrated = append(migrated, obj.GetName())
+						}
+						return c.Update(ctx, obj, opts...)
+					},
+				}).
+				Build()
+			m := &Migrator{
+				Client:     c,
+				Reader:     c,
+				CRD:        testCRD,
+				Checkpoint: testCheckpoint,
+				PageSize:   2,
+			}
+			ctx := context.Background()
+
+			if err := m.Migrate(ctx); (err != nil) != tt.wantErr {
+				t.Fatalf("Migrate() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			sort.Strings(migrated)
+			if !slices.Equal(migrated, tt.wantMigrated) {
+				t.Errorf("migrated %v, want %v", migrated, tt.wantMigrated)
+			}
+
+			crd := &apiextensionsv1.CustomResourceDefinition{}
+			if err := c.Get(ctx, types.NamespacedName{Name: testCRD}, crd); err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(crd.Status.StoredVersions, tt.wantStoredVersions) {
+				t.Errorf("storedVersions = %v, want %v", crd.Status.StoredVersions, tt.wantStoredVersions)
+			}
+
+			checkpoint := &corev1.ConfigMap{}
+			err := c.Get(ctx, testCheckpoint, checkpoint)
+			if tt.wantCheckpoint == nil {
+				if !errors.IsNotFound(err) {
+					t.Errorf("checkpoint = %v, want none", checkpoint.Data)
+				}
+				return
+			}
+			if err != nil {
+				t.Fatal(err)
+			}
+			if !reflect.DeepEqual(checkpoint.Data, tt.wantCheckpoint) {
+				t.Errorf("checkpoint = %v, want %v", checkpoint.Data, tt.wantCheckpoint)
+			}
+		})
+	}
+}
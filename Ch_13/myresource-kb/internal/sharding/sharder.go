@@ -0,0 +1,255 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sharding splits the MyResource instances between several replicas of
+// the manager. Every replica renews its own Lease; the replicas holding a live
+// Lease are sorted and each one owns an equal range of the 32-bit hash space of
+// the object keys. When a replica dies its Lease expires and the remaining
+// replicas take over its range.
+//
+// There is no fencing between the replicas: each one sees a membership change
+// at its own sync, up to one renew interval apart. To keep two replicas from
+// reconciling the same object meanwhile, a replica gives up the keys it loses
+// as soon as it sees the change but only claims the keys it gains one renew
+// interval later, once the others have seen the change too. A replica whose
+// syncs fail for longer than that, e.g. cut off from the API server, can still
+// overlap with the new owner of its keys until its Lease expires.
+package sharding
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/binary"
+	"slices"
+	"sync"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	"k8s.io/apimachinery/pkg/api/errors"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/log"
+)
+
+// GroupLabel marks the Leases taking part in the same sharded deployment.
+const GroupLabel = "mygroup.myid.dev/shard-group"
+
+// Sharder tracks the live replicas and tells whether an object belongs to this one.
+type Sharder struct {
+	// Client writes the Lease of this replica.
+	Client client.Client
+	// Reader reads the Leases of all replicas, it should not be backed by the
+	// cache so that only the Lease namespace needs to be readable.
+	Reader client.Reader
+	// Namespace holds the Leases.
+	Namespace string
+	// Group identifies the replicas sharing the work, e.g. the leader election ID.
+	Group string
+	// Identity is the unique name of this replica, e.g. the Pod name.
+	Identity string
+	// LeaseDuration is how long a replica is considered alive after its last renewal.
+	LeaseDuration time.Duration
+	// RenewInterval is how often the Lease of this replica is renewed.
+	RenewInterval time.Duration
+	// OnRebalance is called once the keys gained after a change of the live
+	// replicas are claimed, so that the objects moving to this replica can be
+	// enqueued.
+	OnRebalance func(ctx context.Context)
+
+	mu      sync.RWMutex
+	members []string
+	// previous are the memberships seen since the keys were last claimed, the
+	// keys gained from them are not claimed yet.
+	previous  [][]string
+	changedAt time.Time
+}
+
+// Start renews the Lease of this replica until ctx is done, then releases it.
+// It implements manager.Runnable.
+func (s *Sharder) Start(ctx context.Context) error {
+	logger := log.FromContext(ctx).WithName("sharder")
+
+	ticker := time.NewTicker(s.RenewInterval)
+	defer ticker.Stop()
+	for {
+		if err := s.sync(ctx); err != nil {
+			logger.Error(err, "unable to sync shard members")
+		}
+		select {
+		case <-ctx.Done():
+			s.release()
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// NeedLeaderElection returns false because every replica must take part.
+func (s *Sharder) NeedLeaderElection() bool {
+	return false
+}
+
+// Owns tells whether the object identified by key is reconciled by this replica.
+// Until the first membership sync, a replica owns nothing. For one renew
+// interval after a membership change, it only owns the keys it owned both
+// before and after the change.
+func (s *Sharder) Owns(key types.NamespacedName) bool {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	if ownerOf(key, s.members) != s.Identity {
+		return false
+	}
+	for _, members := range s.previous {
+		if ownerOf(key, members) != s.Identity {
+			return false
+		}
+	}
+	return true
+}
+
+// Members returns the identities of the live replicas, sorted.
+func (s *Sharder) Members() []string {
+	s.mu.RLock()
+	defer s.mu.RUnlock()
+	return slices.Clone(s.members)
+}
+
+func (s *Sharder) sync(ctx context.Context) error {
+	if err := s.renew(ctx); err != nil {
+		return err
+	}
+
+	leases := coordinationv1.LeaseList{}
+	err := s.Reader.List(ctx, &leases,
+		client.InNamespace(s.Namespace),
+		client.MatchingLabels{GroupLabel: s.Group},
+	)
+	if err != nil {
+		return err
+	}
+	s.update(ctx, liveMembers(leases.Items, time.Now()), time.Now())
+	return nil
+}
+
+// update records the live members seen at now. The keys lost by a change are
+// released at once, the keys gained are claimed at the first update at least
+// one renew interval after the change, which then calls OnRebalance.
+func (s *Sharder) update(ctx context.Context, members []string, now time.Time) {
+	s.mu.Lock()
+	changed := !slices.Equal(members, s.members)
+	if changed {
+		// after further changes before the claim, the keys owned are the ones
+		// owned under every membership seen since the last claim
+		s.previous = append(s.previous, s.members)
+		s.members = members
+		s.changedAt = now
+	}
+	claim := !changed && len(s.previous) > 0 && now.Sub(s.changedAt) >= s.RenewInterval
+	if claim {
+		s.previous = nil
+	}
+	s.mu.Unlock()
+
+	if changed {
+		log.FromContext(ctx).Info("shard members changed", "members", members)
+	}
+	if claim {
+		log.FromContext(ctx).Info("shard members settled", "members", members)
+		if s.OnRebalance != nil {
+			s.OnRebalance(ctx)
+		}
+	}
+}
+
+func (s *Sharder) renew(ctx context.Context) error {
+	now := metav1.NewMicroTime(time.Now())
+	lease := coordinationv1.Lease{}
+	err := s.Reader.Get(ctx, types.NamespacedName{Namespace: s.Namespace, Name: s.leaseName()}, &lease)
+	if errors.IsNotFound(err) {
+		lease = coordinationv1.Lease{
+			ObjectMeta: metav1.ObjectMeta{
+				Name:      s.leaseName(),
+				Namespace: s.Namespace,
+				Labels: map[string]string{
+					GroupLabel: s.Group,
+				},
+			},
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(s.Identity),
+				LeaseDurationSeconds: ptr.To(int32(s.LeaseDuration.Seconds())),
+				AcquireTime:          &now,
+				RenewTime:            &now,
+			},
+		}
+		return s.Client.Create(ctx, &lease)
+	}
+	if err != nil {
+		return err
+	}
+	lease.Spec.RenewTime = &now
+	lease.Spec.LeaseDurationSeconds = ptr.To(int32(s.LeaseDuration.Seconds()))
+	return s.Client.Update(ctx, &lease)
+}
+
+// release deletes the Lease of this replica so that the others take over its
+// range without waiting for the Lease to expire.
+func (s *Sharder) release() {
+	ctx, cancel := context.WithTimeout(context.Background(), s.RenewInterval)
+	defer cancel()
+	lease := coordinationv1.Lease{
+		ObjectMeta: metav1.ObjectMeta{
+			Name:      s.leaseName(),
+			Namespace: s.Namespace,
+		},
+	}
+	_ = client.IgnoreNotFound(s.Client.Delete(ctx, &lease))
+}
+
+func (s *Sharder) leaseName() string {
+	return s.Group + "-" + s.Identity
+}
+
+// liveMembers returns the sorted holders of the Leases renewed within their duration.
+func liveMembers(leases []coordinationv1.Lease, now time.Time) []string {
+	var members []string
+	for _, lease := range leases {
+		spec := lease.Spec
+		if spec.HolderIdentity == nil || spec.RenewTime == nil || spec.LeaseDurationSeconds == nil {
+			continue
+		}
+		expiry := spec.RenewTime.Add(time.Duration(*spec.LeaseDurationSeconds) * time.Second)
+		if now.After(expiry) {
+			continue
+		}
+		members = append(members, *spec.HolderIdentity)
+	}
+	slices.Sort(members)
+	return slices.Compact(members)
+}
+
+// ownerOf maps key into one of len(members) equal ranges of the hash space.
+func ownerOf(key types.NamespacedName, members []string) string {
+	if len(members) == 0 {
+		return ""
+	}
+	// every replica must compute the same hash, hence a fixed function
+	// rather than a seeded one like hash/maphash
+	sum := sha256.Sum256([]byte(key.String()))
+	index := uint64(binary.BigEndian.Uint32(sum[:4])) * uint64(len(members)) >> 32
+	return members[index]
+}
@@ -0,0 +1,192 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sharding
+
+import (
+	"context"
+	"fmt"
+	"reflect"
+	"testing"
+	"time"
+
+	coordinationv1 "k8s.io/api/coordination/v1"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/types"
+	"k8s.io/utils/ptr"
+)
+
+func Test_liveMembers(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	lease := func(holder string, renewed time.Duration) coordinationv1.Lease {
+		renewTime := metav1.NewMicroTime(now.Add(-renewed))
+		return coordinationv1.Lease{
+			Spec: coordinationv1.LeaseSpec{
+				HolderIdentity:       ptr.To(holder),
+				LeaseDurationSeconds: ptr.To(int32(15)),
+				RenewTime:            &renewTime,
+			},
+		}
+	}
+
+	tests := []struct {
+		name   string
+		leases []coordinationv1.Lease
+		want   []string
+	}{
+		{
+			name: "case 1: no lease",
+			want: nil,
+		},
+		{
+			name: "case 2: sorted live members",
+			leases: []coordinationv1.Lease{
+				lease("manager-c", time.Second),
+				lease("manager-a", 10*time.Second),
+			},
+			want: []string{"manager-a", "manager-c"},
+		},
+		{
+			name: "case 3: expired and incomplete leases are dropped",
+			leases: []coordinationv1.Lease{
+				lease("manager-a", time.Second),
+				lease("manager-b", time.Minute),
+				{},
+			},
+			want: []string{"manager-a"},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := liveMembers(tt.leases, now); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("liveMembers() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func Test_ownerOf(t *testing.T) {
+	members := []string{"manager-a", "manager-b", "manager-c"}
+
+	if got := ownerOf(types.NamespacedName{Namespace: "default", Name: "myres"}, nil); got != "" {
+		t.Errorf("ownerOf() without members = %q, want none", got)
+	}
+
+	counts := map[string]int{}
+	for i := 0; i < 3000; i++ {
+		key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("myres-%d", i)}
+		owner := ownerOf(key, members)
+		if again := ownerOf(key, members); again != owner {
+			t.Fatalf("ownerOf(%s) is not stable: %q then %q", key, owner, again)
+		}
+		counts[owner]++
+	}
+	for _, member := range members {
+		// each replica should get roughly a third of the keys
+		if counts[member] < 800 || counts[member] > 1200 {
+			t.Errorf("ownerOf() gave %d keys to %s, want about 1000", counts[member], member)
+		}
+	}
+}
+
+func Test_Sharder_update(t *testing.T) {
+	now := time.Date(2024, 8, 1, 12, 0, 0, 0, time.UTC)
+	rebalances := 0
+	s := &Sharder{
+		Identity:      "manager-a",
+		RenewInterval: 5 * time.Second,
+		OnRebalance:   func(context.Context) { rebalances++ },
+	}
+	ctx := context.Background()
+	one := []string{"manager-a"}
+	two := []string{"manager-a", "manager-b"}
+
+	// a key kept by manager-a and a key moving to manager-b when it joins
+	var kept, moved types.NamespacedName
+	for i := 0; kept.Name == "" || moved.Name == ""; i++ {
+		key := types.NamespacedName{Namespace: "default", Name: fmt.Sprintf("myres-%d", i)}
+		if ownerOf(key, two) == "manager-a" {
+			kept = key
+		} else {
+			moved = key
+		}
+	}
+
+	tests := []struct {
+		name           string
+		members        []string
+		after          time.Duration
+		wantKept       bool
+		wantMoved      bool
+		wantRebalances int
+	}{
+		{
+			name:    "case 1: first sync, nothing claimed yet",
+			members: one,
+		},
+		{
+			name:           "case 2: claimed after a renew interval",
+			members:        one,
+			after:          5 * time.Second,
+			wantKept:       true,
+			wantMoved:      true,
+			wantRebalances: 1,
+		},
+		{
+			name:           "case 3: keys lost to a new member released at once",
+			members:        two,
+			after:          10 * time.Second,
+			wantKept:       true,
+			wantRebalances: 1,
+		},
+		{
+			name:           "case 4: member gone, its keys not claimed before a renew interval",
+			members:        one,
+			after:          12 * time.Second,
+			wantKept:       true,
+			wantRebalances: 1,
+		},
+		{
+			name:           "case 5: not settled within the renew interval",
+			members:        one,
+			after:          16 * time.Second,
+			wantKept:       true,
+			wantRebalances: 1,
+		},
+		{
+			name:           "case 6: settled",
+			members:        one,
+			after:          17 * time.Second,
+			wantKept:       true,
+			wantMoved:      true,
+			wantRebalances: 2,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			s.update(ctx, tt.members, now.Add(tt.after))
+			if got := s.Owns(kept); got != tt.wantKept {
+				t.Errorf("Owns(%s) = %v, want %v", kept, got, tt.wantKept)
+			}
+			if got := s.Owns(moved); got != tt.wantMoved {
+				t.Errorf("Owns(%s) = %v, want %v", moved, got, tt.wantMoved)
+			}
+			if rebalances != tt.wantRebalances {
+				t.Errorf("# of rebalances = %d, want %d", rebalances, tt.wantRebalances)
+			}
+		})
+	}
+}
@@ -0,0 +1,94 @@
+package tracing
+
+import (
+	"bytes"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"go.opentelemetry.io/otel"
+	"k8s.io/client-go/rest"
+)
+
+func Test_Setup(t *testing.T) {
+	tests := []struct {
+		name    string
+		opts    Options
+		wantErr bool
+	}{
+		{
+			name: "case 1: disabled",
+			opts: Options{ServiceName: "test"},
+		},
+		{
+			name:    "case 2: endpoint and stdout",
+			opts:    Options{ServiceName: "test", Endpoint: "localhost:4317", Stdout: &bytes.Buffer{}},
+			wantErr: true,
+		},
+		{
+			name: "case 3: endpoint",
+			opts: Options{ServiceName: "test", Endpoint: "localhost:4317", Insecure: true},
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			shutdown, err := Setup(context.Background(), tt.opts)
+			if (err != nil) != tt.wantErr {
+				t.Fatalf("Setup() error = %v, wantErr %v", err, tt.wantErr)
+			}
+			if err != nil {
+				return
+			}
+			ctx, cancel := context.WithCancel(context.Background())
+			// nothing listens on the endpoint, don't wait for the export
+			cancel()
+			_ = shutdown(ctx)
+		})
+	}
+}
+
+func Test_WrapConfig(t *testing.T) {
+	var traceparent string
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		traceparent = r.Header.Get("traceparent")
+	}))
+	defer server.Close()
+
+	out := &bytes.Buffer{}
+	shutdown, err := Setup(context.Background(), Options{ServiceName: "test", Stdout: out})
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	cfg := &rest.Config{Host: server.URL}
+	WrapConfig(cfg)
+	client, err := rest.HTTPClientFor(cfg)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	ctx, span := otel.Tracer("test").Start(context.Background(), "caller")
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server.URL+"/version", nil)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		t.Fatal(err)
+	}
+	resp.Body.Close()
+	span.End()
+	if err := shutdown(context.Background()); err != nil {
+		t.Fatal(err)
+	}
+
+	traceID := span.SpanContext().TraceID().String()
+	if !strings.Contains(traceparent, traceID) {
+		t.Errorf("WrapConfig() traceparent = %q, want trace %s", traceparent, traceID)
+	}
+	if !strings.Contains(out.String(), `"Name":"caller"`) {
+		t.Errorf("Setup() exported %s, want the caller span", out.String())
+	}
+}
@@ -31,6 +31,10 @@ const namespace = "myresource-kb-system"
 
 var _ = Describe("controller", Ordered, func() {
 	BeforeAll(func() {
+		if utils.Offline() {
+			Skip("the kind e2e tests do not run in the offline mode")
+		}
+
 		By("installing prometheus operator")
 		Expect(utils.InstallPrometheusOperator()).To(Succeed())
 
@@ -43,6 +47,10 @@ var _ = Describe("controller", Ordered, func() {
 	})
 
 	AfterAll(func() {
+		if utils.Offline() {
+			return
+		}
+
 		By("uninstalling the Prometheus manager bundle")
 		utils.UninstallPrometheusOperator()
 
@@ -0,0 +1,201 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package e2e
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"fmt"
+	"net/http"
+	"os/exec"
+	"path/filepath"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2"
+	. "github.com/onsi/gomega"
+
+	appsv1 "k8s.io/api/apps/v1"
+	corev1 "k8s.io/api/core/v1"
+	"k8s.io/apimachinery/pkg/api/resource"
+	metav1 "k8s.io/apimachinery/pkg/apis/meta/v1"
+	"k8s.io/apimachinery/pkg/runtime"
+	"k8s.io/apimachinery/pkg/types"
+	clientgoscheme "k8s.io/client-go/kubernetes/scheme"
+	"sigs.k8s.io/controller-runtime/pkg/client"
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+
+	mygroupv1alpha1 "github.com/myid/myresource/api/v1alpha1"
+	"github.com/myid/myresource/test/utils"
+)
+
+// The offline mode replaces kind with envtest, the Prometheus scrape with a
+// plain HTTP GET of the metrics endpoint and cert-manager with the built-in
+// certificate rotation of the manager. Outside of a cluster the manager runs
+// in the "default" namespace.
+var _ = Describe("controller (offline)", Ordered, func() {
+	const (
+		managerNamespace = "default"
+		webhookService   = "myresource-kb-webhook-service"
+	)
+
+	var (
+		testEnv     *envtest.Environment
+		k8sClient   client.Client
+		cancel      context.CancelFunc
+		manager     *exec.Cmd
+		metricsAddr string
+		probeAddr   string
+		webhookPort int
+	)
+
+	BeforeAll(func() {
+		if !utils.Offline() {
+			Skip(fmt.Sprintf("set %s=true to run the e2e tests against envtest", utils.OfflineEnv))
+		}
+		dir := GinkgoT().TempDir()
+
+		By("starting the control plane")
+		var kubeconfig string
+		var err error
+		testEnv, kubeconfig, err = utils.StartEnvtest(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		scheme := runtime.NewScheme()
+		Expect(clientgoscheme.AddToScheme(scheme)).To(Succeed())
+		Expect(mygroupv1alpha1.AddToScheme(scheme)).To(Succeed())
+		k8sClient, err = client.New(testEnv.Config, client.Options{Scheme: scheme})
+		Expect(err).NotTo(HaveOccurred())
+
+		By("building the manager")
+		binary, err := utils.BuildManager(dir)
+		Expect(err).NotTo(HaveOccurred())
+
+		By("starting the manager")
+		ports := make([]int, 3)
+		for i := range ports {
+			ports[i], err = utils.FreePort()
+			Expect(err).NotTo(HaveOccurred())
+		}
+		metricsAddr = fmt.Sprintf("127.0.0.1:%d", ports[0])
+		probeAddr = fmt.Sprintf("127.0.0.1:%d", ports[1])
+		webhookPort = ports[2]
+
+		var ctx context.Context
+		ctx, cancel = context.WithCancel(context.Background())
+		manager, err = utils.StartManager(ctx, binary, kubeconfig,
+			"--metrics-bind-address="+metricsAddr,
+			"--metrics-secure=false",
+			"--health-probe-bind-address="+probeAddr,
+			"--enable-webhooks",
+			"--enable-cert-rotation",
+			"--webhook-cert-dir="+filepath.Join(dir, "certs"),
+			fmt.Sprintf("--webhook-port=%d", webhookPort),
+			"--webhook-service-name="+webhookService,
+		)
+		Expect(err).NotTo(HaveOccurred())
+	})
+
+	AfterAll(func() {
+		if cancel != nil {
+			cancel()
+		}
+		if manager != nil {
+			_ = manager.Wait()
+		}
+		if testEnv != nil {
+			By("stopping the control plane")
+			Expect(testEnv.Stop()).To(Succeed())
+		}
+	})
+
+	It("should run successfully", func() {
+		By("validating that the manager is ready")
+		Eventually(func() error {
+			resp, err := http.Get("http://" + probeAddr + "/readyz")
+			if err != nil {
+				return err
+			}
+			defer resp.Body.Close()
+			if resp.StatusCode != http.StatusOK {
+				return fmt.Errorf("readyz returned %s", resp.Status)
+			}
+			return nil
+		}, time.Minute, time.Second).Should(Succeed())
+	})
+
+	It("should provision the webhook certificates", func() {
+		By("reading the CA from the certificate Secret")
+		secret := &corev1.Secret{}
+		Eventually(func() error {
+			return k8sClient.Get(context.Background(),
+				types.NamespacedName{Namespace: managerNamespace, Name: "webhook-server-cert"}, secret)
+		}, time.Minute, time.Second).Should(Succeed())
+		roots := x509.NewCertPool()
+		Expect(roots.AppendCertsFromPEM(secret.Data["ca.crt"])).To(BeTrue())
+
+		By("verifying the webhook server against the CA")
+		Eventually(func() error {
+			conn, err := tls.Dial("tcp", fmt.Sprintf("127.0.0.1:%d", webhookPort), &tls.Config{
+				RootCAs:    roots,
+				ServerName: webhookService + "." + managerNamespace + ".svc",
+			})
+			if err != nil {
+				return err
+			}
+			return conn.Close()
+		}, time.Minute, time.Second).Should(Succeed())
+	})
+
+	It("should reconcile a MyResource", func() {
+		ctx := context.Background()
+		key := types.NamespacedName{Namespace: "default", Name: "e2e"}
+
+		By("creating a MyResource")
+		myres := &mygroupv1alpha1.MyResource{
+			ObjectMeta: metav1.ObjectMeta{Namespace: key.Namespace, Name: key.Name},
+			Spec: mygroupv1alpha1.MyResourceSpec{
+				Image:  "nginx:1.27",
+				Memory: resource.MustParse("64Mi"),
+			},
+		}
+		Expect(k8sClient.Create(ctx, myres)).To(Succeed())
+
+		By("waiting for the owned Deployment")
+		deploy := &appsv1.Deployment{}
+		Eventually(func() error {
+			return k8sClient.Get(ctx, types.NamespacedName{Namespace: key.Namespace, Name: "e2e-deployment"}, deploy)
+		}, time.Minute, time.Second).Should(Succeed())
+		Expect(deploy.Spec.Template.Spec.Containers[0].Image).To(Equal("nginx:1.27"))
+
+		By("reporting the Deployment ready, there is no Deployment controller in envtest")
+		deploy.Status.Replicas = 1
+		deploy.Status.ReadyReplicas = 1
+		Expect(k8sClient.Status().Update(ctx, deploy)).To(Succeed())
+
+		Eventually(func() (string, error) {
+			err := k8sClient.Get(ctx, key, myres)
+			return myres.Status.State, err
+		}, time.Minute, time.Second).Should(Equal("Ready"))
+	})
+
+	It("should expose the reconcile metrics", func() {
+		Eventually(func() (string, error) {
+			return utils.ScrapeMetrics(metricsAddr)
+		}, time.Minute, time.Second).Should(ContainSubstring(`controller_runtime_reconcile_total{controller="myresource"`))
+	})
+})
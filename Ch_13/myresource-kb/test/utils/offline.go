@@ -0,0 +1,129 @@
+/*
+Copyright 2024.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package utils
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strconv"
+	"time"
+
+	. "github.com/onsi/ginkgo/v2" //nolint:golint,revive
+
+	"sigs.k8s.io/controller-runtime/pkg/envtest"
+)
+
+// OfflineEnv selects the offline e2e mode, which needs neither kind nor
+// network access: the control plane is started by envtest and the manager
+// binary built from this tree runs as a local process against it.
+const OfflineEnv = "E2E_OFFLINE"
+
+// Offline reports whether the e2e suite runs in the offline mode.
+func Offline() bool {
+	offline, _ := strconv.ParseBool(os.Getenv(OfflineEnv))
+	return offline
+}
+
+// StartEnvtest starts a control plane with the CRDs of the project and writes
+// a kubeconfig of a cluster-admin user to dir.
+func StartEnvtest(dir string) (*envtest.Environment, string, error) {
+	projectDir, err := GetProjectDir()
+	if err != nil {
+		return nil, "", err
+	}
+	testEnv := &envtest.Environment{
+		CRDDirectoryPaths:     []string{filepath.Join(projectDir, "config", "crd", "bases")},
+		ErrorIfCRDPathMissing: true,
+	}
+	if _, err := testEnv.Start(); err != nil {
+		return nil, "", err
+	}
+
+	user, err := testEnv.AddUser(envtest.User{Name: "e2e-manager", Groups: []string{"system:masters"}}, nil)
+	if err != nil {
+		return testEnv, "", err
+	}
+	kubeconfig, err := user.KubeConfig()
+	if err != nil {
+		return testEnv, "", err
+	}
+	path := filepath.Join(dir, "kubeconfig")
+	if err := os.WriteFile(path, kubeconfig, 0o600); err != nil {
+		return testEnv, "", err
+	}
+	return testEnv, path, nil
+}
+
+// BuildManager builds the manager binary into dir and returns its path.
+func BuildManager(dir string) (string, error) {
+	binary := filepath.Join(dir, "manager")
+	cmd := exec.Command("go", "build", "-o", binary, "./cmd")
+	if _, err := Run(cmd); err != nil {
+		return "", err
+	}
+	return binary, nil
+}
+
+// StartManager runs the manager binary against the cluster of kubeconfig until
+// ctx is done. Its output goes to the GinkgoWriter.
+func StartManager(ctx context.Context, binary, kubeconfig string, args ...string) (*exec.Cmd, error) {
+	cmd := exec.CommandContext(ctx, binary, args...)
+	cmd.Env = append(os.Environ(), "KUBECONFIG="+kubeconfig)
+	cmd.Stdout = GinkgoWriter
+	cmd.Stderr = GinkgoWriter
+	cmd.WaitDelay = 10 * time.Second
+	_, _ = fmt.Fprintf(GinkgoWriter, "starting: %s %v\n", binary, args)
+	if err := cmd.Start(); err != nil {
+		return nil, err
+	}
+	return cmd, nil
+}
+
+// FreePort returns a local TCP port nobody listens on.
+func FreePort() (int, error) {
+	l, err := net.Listen("tcp", "127.0.0.1:0")
+	if err != nil {
+		return 0, err
+	}
+	defer l.Close()
+	return l.Addr().(*net.TCPAddr).Port, nil
+}
+
+// ScrapeMetrics returns the metrics served over HTTP at addr, it stands in
+// for the Prometheus scrape of the cluster mode.
+func ScrapeMetrics(addr string) (string, error) {
+	client := &http.Client{Timeout: 5 * time.Second}
+	resp, err := client.Get("http://" + addr + "/metrics")
+	if err != nil {
+		return "", err
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return "", err
+	}
+	if resp.StatusCode != http.StatusOK {
+		return "", fmt.Errorf("scraping %s: %s", addr, resp.Status)
+	}
+	return string(body), nil
+}